@@ -1,6 +1,7 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"log/slog"
 	"net/http"
@@ -8,17 +9,57 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/guluzadehh/go_chat/internal/config"
+	adminlockouts "github.com/guluzadehh/go_chat/internal/http/handlers/admin/lockouts"
 	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/login"
+	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/logintotp"
 	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/logout"
+	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/logoutall"
 	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/refresh"
+	resetconfirm "github.com/guluzadehh/go_chat/internal/http/handlers/auth/reset/confirm"
+	resetrequest "github.com/guluzadehh/go_chat/internal/http/handlers/auth/reset/request"
 	"github.com/guluzadehh/go_chat/internal/http/handlers/auth/signup"
 	"github.com/guluzadehh/go_chat/internal/http/handlers/chat"
+	dmlist "github.com/guluzadehh/go_chat/internal/http/handlers/dm/list"
+	dmopen "github.com/guluzadehh/go_chat/internal/http/handlers/dm/open"
+	"github.com/guluzadehh/go_chat/internal/http/handlers/jwks"
+	medelete "github.com/guluzadehh/go_chat/internal/http/handlers/me/delete"
+	meget "github.com/guluzadehh/go_chat/internal/http/handlers/me/get"
+	mentionlist "github.com/guluzadehh/go_chat/internal/http/handlers/me/mentions/list"
+	mentionread "github.com/guluzadehh/go_chat/internal/http/handlers/me/mentions/read"
+	mepassword "github.com/guluzadehh/go_chat/internal/http/handlers/me/password"
+	meupdate "github.com/guluzadehh/go_chat/internal/http/handlers/me/update"
+	roomban "github.com/guluzadehh/go_chat/internal/http/handlers/room/ban"
 	roomcreate "github.com/guluzadehh/go_chat/internal/http/handlers/room/create"
 	roomdelete "github.com/guluzadehh/go_chat/internal/http/handlers/room/delete"
+	invitecreate "github.com/guluzadehh/go_chat/internal/http/handlers/room/invite/create"
+	invitelist "github.com/guluzadehh/go_chat/internal/http/handlers/room/invite/list"
+	inviterevoke "github.com/guluzadehh/go_chat/internal/http/handlers/room/invite/revoke"
+	roomkick "github.com/guluzadehh/go_chat/internal/http/handlers/room/kick"
 	roomlist "github.com/guluzadehh/go_chat/internal/http/handlers/room/list"
+	roommembers "github.com/guluzadehh/go_chat/internal/http/handlers/room/members"
+	messagedelete "github.com/guluzadehh/go_chat/internal/http/handlers/room/message/delete"
+	messageedit "github.com/guluzadehh/go_chat/internal/http/handlers/room/message/edit"
+	messagefollow "github.com/guluzadehh/go_chat/internal/http/handlers/room/message/follow"
+	messagereplies "github.com/guluzadehh/go_chat/internal/http/handlers/room/message/replies"
+	messageunfollow "github.com/guluzadehh/go_chat/internal/http/handlers/room/message/unfollow"
+	roommessages "github.com/guluzadehh/go_chat/internal/http/handlers/room/messages"
+	roommute "github.com/guluzadehh/go_chat/internal/http/handlers/room/mute"
+	roomread "github.com/guluzadehh/go_chat/internal/http/handlers/room/read"
+	roomrole "github.com/guluzadehh/go_chat/internal/http/handlers/room/role"
+	roomunban "github.com/guluzadehh/go_chat/internal/http/handlers/room/unban"
+	roomunmute "github.com/guluzadehh/go_chat/internal/http/handlers/room/unmute"
+	totpconfirm "github.com/guluzadehh/go_chat/internal/http/handlers/totp/confirm"
+	totpenroll "github.com/guluzadehh/go_chat/internal/http/handlers/totp/enroll"
+	totprecovery "github.com/guluzadehh/go_chat/internal/http/handlers/totp/recovery"
+	userget "github.com/guluzadehh/go_chat/internal/http/handlers/user/get"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/adminmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/loggingmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
+	"github.com/guluzadehh/go_chat/internal/lib/notify"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/storage/redis"
 	"github.com/guluzadehh/go_chat/internal/storage/sqlite"
@@ -29,6 +70,9 @@ const env_local = "local"
 const env_dev = "dev"
 const env_prod = "prod"
 
+const broker_local = "local"
+const broker_redis = "redis"
+
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
@@ -42,6 +86,12 @@ func main() {
 	log := setupLogger(config.Env)
 	log.Info("starting go-chat app", slog.String("env", config.Env))
 
+	// jwt
+	if err := jwt.LoadKeys(config); err != nil {
+		log.Error("failed to load jwt keys", sl.Err(err))
+		os.Exit(1)
+	}
+
 	// storage
 	sqliteStorage, err := sqlite.New(config.StoragePath)
 	if err != nil {
@@ -55,29 +105,115 @@ func main() {
 		os.Exit(1)
 	}
 
+	// chat
+	var broker roomchat.Broker
+	var presence roomchat.Presence
+	switch config.Chat.Broker {
+	case broker_local:
+		broker = roomchat.NewLocalBroker()
+		presence = roomchat.NewLocalPresence()
+	case broker_redis:
+		broker = redisStorage
+		presence = redisStorage
+	default:
+		log.Error("unknown chat broker", slog.String("broker", config.Chat.Broker))
+		os.Exit(1)
+	}
+
+	hub, err := roomchat.NewHub(log, config, sqliteStorage, redisStorage, broker, presence)
+	if err != nil {
+		log.Error("failed to init chat hub", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// auth
+	loginLimiter := loginlimit.New(redisStorage, config)
+	resetLimiter := loginlimit.NewReset(redisStorage, config)
+
+	// account
+	switch config.Account.OwnedRooms {
+	case medelete.OwnedRoomsDelete, medelete.OwnedRoomsTransfer:
+	default:
+		log.Error("unknown owned rooms policy", slog.String("owned_rooms", config.Account.OwnedRooms))
+		os.Exit(1)
+	}
+
+	notifier, err := notify.New(log, config)
+	if err != nil {
+		log.Error("failed to init notifier", sl.Err(err))
+		os.Exit(1)
+	}
+
 	// router
 	router := mux.NewRouter()
 
 	router.Use(requestmdw.AddRequestId)
 	router.Use(loggingmdw.LogRequests(log))
 
+	router.Handle("/.well-known/jwks.json", jwks.New(log)).Methods("GET")
+
 	// Public routes
 	api := router.PathPrefix("/api").Subrouter()
 
-	api.Handle("/login", login.New(log, config, sqliteStorage)).Methods("POST")
+	api.Handle("/login", login.New(log, config, loginLimiter, sqliteStorage, redisStorage)).Methods("POST")
+	api.Handle("/login/totp", logintotp.New(log, config, loginLimiter, sqliteStorage, redisStorage, redisStorage)).Methods("POST")
 	api.Handle("/signup", signup.New(log, sqliteStorage)).Methods("POST")
-	api.Handle("/refresh", refresh.New(log, config)).Methods("POST")
+	api.Handle("/refresh", refresh.New(log, config, sqliteStorage, redisStorage)).Methods("POST")
+	api.Handle("/password/reset", resetrequest.New(log, config, resetLimiter, notifier, sqliteStorage, redisStorage)).Methods("POST")
+	api.Handle("/password/reset/confirm", resetconfirm.New(log, hub, sqliteStorage, redisStorage)).Methods("POST")
 
 	// Protected routes
 	apiAuth := api.NewRoute().Subrouter()
-	apiAuth.Use(authmdw.Authorize(log, config, sqliteStorage))
+	apiAuth.Use(authmdw.Authorize(log, config, sqliteStorage, redisStorage))
+
+	apiAuth.Handle("/logout", logout.New(log, config, redisStorage)).Methods("POST")
+	apiAuth.Handle("/logout/all", logoutall.New(log, config, hub, redisStorage)).Methods("POST")
+	apiAuth.Handle("/me", meget.New(log)).Methods("GET")
+	apiAuth.Handle("/me", meupdate.New(log, sqliteStorage)).Methods("PATCH")
+	apiAuth.Handle("/me", medelete.New(log, config, hub, sqliteStorage, redisStorage, redisStorage)).Methods("DELETE")
+	apiAuth.Handle("/me/mentions", mentionlist.New(log, sqliteStorage)).Methods("GET")
+	apiAuth.Handle("/me/mentions/read", mentionread.New(log, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/me/password", mepassword.New(log, config, hub, loginLimiter, sqliteStorage, redisStorage)).Methods("PUT")
+	apiAuth.Handle("/me/totp", totpenroll.New(log, config, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/me/totp/confirm", totpconfirm.New(log, config, loginLimiter, sqliteStorage, redisStorage)).Methods("POST")
+	apiAuth.Handle("/me/totp/recovery-codes", totprecovery.New(log, config, loginLimiter, sqliteStorage, redisStorage)).Methods("POST")
+
+	apiAuth.Handle("/users/{user_id:[0-9]+}", userget.New(log, sqliteStorage)).Methods("GET")
+
+	apiAuth.Handle("/dm", dmlist.New(log, redisStorage, sqliteStorage, sqliteStorage)).Methods("GET")
+	apiAuth.Handle("/dm/{user_id:[0-9]+}", dmopen.New(log, redisStorage, sqliteStorage, sqliteStorage)).Methods("POST")
 
-	apiAuth.Handle("/logout", logout.New(log, config)).Methods("POST")
 	apiAuth.Handle("/rooms", roomcreate.New(log, redisStorage)).Methods("POST")
-	apiAuth.Handle("/rooms", roomlist.New(log, redisStorage, sqliteStorage)).Methods("GET")
+	apiAuth.Handle("/rooms", roomlist.New(log, redisStorage, sqliteStorage, hub, sqliteStorage)).Methods("GET")
 	apiAuth.Handle("/rooms/{room_uuid}", roomdelete.New(log, redisStorage)).Methods("DELETE")
 
-	apiAuth.Handle("/rooms/{room_uuid}/chat", chat.New(log, config, redisStorage)).Methods("GET")
+	apiAuth.Handle("/rooms/{room_uuid}/members", roommembers.New(log, hub, redisStorage)).Methods("GET")
+	apiAuth.Handle("/rooms/{room_uuid}/messages", roommessages.New(log, redisStorage, sqliteStorage, sqliteStorage)).Methods("GET")
+	apiAuth.Handle("/rooms/{room_uuid}/messages/{message_id:[0-9]+}", messageedit.New(log, hub, redisStorage)).Methods("PATCH")
+	apiAuth.Handle("/rooms/{room_uuid}/messages/{message_id:[0-9]+}", messagedelete.New(log, hub, redisStorage)).Methods("DELETE")
+	apiAuth.Handle("/rooms/{room_uuid}/messages/{message_id:[0-9]+}/replies", messagereplies.New(log, redisStorage, sqliteStorage, sqliteStorage)).Methods("GET")
+	apiAuth.Handle("/rooms/{room_uuid}/messages/{message_id:[0-9]+}/follow", messagefollow.New(log, hub, redisStorage)).Methods("PUT")
+	apiAuth.Handle("/rooms/{room_uuid}/messages/{message_id:[0-9]+}/follow", messageunfollow.New(log, hub, redisStorage)).Methods("DELETE")
+	apiAuth.Handle("/rooms/{room_uuid}/read", roomread.New(log, hub, redisStorage)).Methods("POST")
+	apiAuth.Handle("/rooms/{room_uuid}/roles/{user_id:[0-9]+}", roomrole.New(log, redisStorage, sqliteStorage)).Methods("PUT")
+	apiAuth.Handle("/rooms/{room_uuid}/kicks", roomkick.New(log, hub, redisStorage, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/rooms/{room_uuid}/bans", roomban.New(log, hub, redisStorage, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/rooms/{room_uuid}/bans/{user_id:[0-9]+}", roomunban.New(log, hub, redisStorage, sqliteStorage)).Methods("DELETE")
+	apiAuth.Handle("/rooms/{room_uuid}/mutes", roommute.New(log, hub, redisStorage, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/rooms/{room_uuid}/mutes/{user_id:[0-9]+}", roomunmute.New(log, hub, redisStorage, sqliteStorage)).Methods("DELETE")
+
+	apiAuth.Handle("/rooms/{room_uuid}/invites", invitecreate.New(log, config, redisStorage, sqliteStorage)).Methods("POST")
+	apiAuth.Handle("/rooms/{room_uuid}/invites", invitelist.New(log, redisStorage)).Methods("GET")
+	apiAuth.Handle("/rooms/{room_uuid}/invites/{invite_id}", inviterevoke.New(log, redisStorage)).Methods("DELETE")
+
+	apiAuth.Handle("/rooms/{room_uuid}/chat", chat.New(log, config, hub, redisStorage)).Methods("GET")
+
+	// Admin routes
+	apiAdmin := apiAuth.PathPrefix("/admin").Subrouter()
+	apiAdmin.Use(adminmdw.RequireAdmin(log))
+
+	apiAdmin.Handle("/lockouts", adminlockouts.New(log, redisStorage)).Methods("GET")
+	apiAdmin.Handle("/debug/vars", expvar.Handler()).Methods("GET")
 
 	// run
 	log.Info("starting server listener", slog.String("addr", config.HTTPServer.Address))
@@ -9,12 +9,16 @@ import (
 )
 
 type Config struct {
-	Env         string     `yaml:"env" env-required:"true"`
-	StoragePath string     `yaml:"storage_path" env-required:"true"`
-	JWT         JWTCfg     `yaml:"jwt"`
-	HTTPServer  HTTPServer `yaml:"http_server"`
-	Redis       RedisCfg   `yaml:"redis"`
-	Chat        Chat       `yaml:"chat"`
+	Env         string      `yaml:"env" env-required:"true"`
+	StoragePath string      `yaml:"storage_path" env-required:"true"`
+	JWT         JWTCfg      `yaml:"jwt"`
+	HTTPServer  HTTPServer  `yaml:"http_server"`
+	Redis       RedisCfg    `yaml:"redis"`
+	Chat        Chat        `yaml:"chat"`
+	Login       LoginCfg    `yaml:"login"`
+	TOTP        TOTPCfg     `yaml:"totp"`
+	Account     AccountCfg  `yaml:"account"`
+	Notifier    NotifierCfg `yaml:"notifier"`
 }
 
 type HTTPServer struct {
@@ -24,9 +28,24 @@ type HTTPServer struct {
 }
 
 type JWTCfg struct {
-	SecretKey string     `yaml:"-" env:"JWT_SECRET_KEY" env-required:"true"`
-	Access    AccessCfg  `yaml:"access"`
-	Refresh   RefreshCfg `yaml:"refresh"`
+	Issuer    string       `yaml:"issuer" env-default:"gochat"`
+	Audience  string       `yaml:"audience" env-default:"gochat"`
+	Keys      KeysCfg      `yaml:"keys"`
+	Access    AccessCfg    `yaml:"access"`
+	Refresh   RefreshCfg   `yaml:"refresh"`
+	Challenge ChallengeCfg `yaml:"challenge"`
+}
+
+// ChallengeCfg is the lifetime of the token exchanged for a second factor on login.
+type ChallengeCfg struct {
+	Expire time.Duration `yaml:"expire" env-default:"5m"`
+}
+
+// KeysCfg points to the directory of PEM encoded RSA and Ed25519 keys, named <kid>.pem.
+// Public keys only verify tokens, SigningKeyId picks the private key tokens are signed with.
+type KeysCfg struct {
+	Dir          string `yaml:"dir" env-default:"./keys"`
+	SigningKeyId string `yaml:"signing_key_id" env-required:"true"`
 }
 
 type AccessCfg struct {
@@ -46,14 +65,74 @@ type RedisCfg struct {
 }
 
 type Chat struct {
-	Room       RoomCfg       `yaml:"room"`
-	PongWait   time.Duration `yaml:"pong_wait" env-default:"60s"`
-	PingPeriod time.Duration `yaml:"ping_period" env-default:"54s"`
-	WriteWait  time.Duration `yaml:"write_wait" env-default:"10s"`
+	Room          RoomCfg       `yaml:"room"`
+	Broker        string        `yaml:"broker" env-default:"local"`
+	DedupWindow   time.Duration `yaml:"dedup_window" env-default:"5m"`
+	SendQueueSize int           `yaml:"send_queue_size" env-default:"64"`
+	PongWait      time.Duration `yaml:"pong_wait" env-default:"60s"`
+	PingPeriod    time.Duration `yaml:"ping_period" env-default:"54s"`
+	WriteWait     time.Duration `yaml:"write_wait" env-default:"10s"`
+	Typing        TypingCfg     `yaml:"typing"`
+	EditWindow    time.Duration `yaml:"edit_window" env-default:"15m"`
+	MaxReactions  int           `yaml:"max_reactions" env-default:"20"`
+	Presence      PresenceCfg   `yaml:"presence"`
+}
+
+// PresenceCfg is how often an instance refreshes the presence of its connections, the
+// presence of an instance that stops refreshing it expires after Expire.
+type PresenceCfg struct {
+	Heartbeat time.Duration `yaml:"heartbeat" env-default:"10s"`
+	Expire    time.Duration `yaml:"expire" env-default:"30s"`
+}
+
+// TypingCfg limits how often the typing events of a member are broadcast, a member that
+// hasn't sent a stop is no longer typing after Timeout.
+type TypingCfg struct {
+	Throttle time.Duration `yaml:"throttle" env-default:"2s"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"6s"`
 }
 
 type RoomCfg struct {
-	Capacity int `yaml:"capacity" env-default:"16"`
+	Capacity    int `yaml:"capacity" env-default:"16"`
+	HistorySize int `yaml:"history_size" env-default:"100"`
+	ReplayLimit int `yaml:"replay_limit" env-default:"200"`
+}
+
+// LoginCfg limits the failed login attempts per username and per client ip. Once the
+// attempts within Window are used up, logins are locked for Lockout, doubling with every
+// further failure up to MaxLockout.
+type LoginCfg struct {
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	MaxIpAttempts int           `yaml:"max_ip_attempts" env-default:"20"`
+	Window        time.Duration `yaml:"window" env-default:"15m"`
+	Lockout       time.Duration `yaml:"lockout" env-default:"1m"`
+	MaxLockout    time.Duration `yaml:"max_lockout" env-default:"1h"`
+}
+
+type TOTPCfg struct {
+	Issuer        string `yaml:"issuer" env-default:"gochat"`
+	RecoveryCodes int    `yaml:"recovery_codes" env-default:"10"`
+}
+
+// AccountCfg sets what happens to the rooms of deleted users, they are either deleted or
+// transferred to one of their moderators or members.
+type AccountCfg struct {
+	OwnedRooms          string        `yaml:"owned_rooms" env-default:"transfer"`
+	PasswordResetExpire time.Duration `yaml:"password_reset_expire" env-default:"30m"`
+}
+
+// NotifierCfg picks how users are notified: log, file or smtp.
+type NotifierCfg struct {
+	Kind     string  `yaml:"kind" env-default:"log"`
+	FilePath string  `yaml:"file_path" env-default:"./storage/notifications.log"`
+	SMTP     SMTPCfg `yaml:"smtp"`
+}
+
+type SMTPCfg struct {
+	Address  string `yaml:"address" env-default:"localhost:25"`
+	Username string `yaml:"username"`
+	Password string `yaml:"-" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from"`
 }
 
 func MustLoad() *Config {
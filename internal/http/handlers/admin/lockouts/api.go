@@ -0,0 +1,16 @@
+package adminlockouts
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	Lockouts []*types.LoginLockoutView `json:"lockouts"`
+	Size     int                       `json:"size"`
+}
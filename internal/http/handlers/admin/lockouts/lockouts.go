@@ -0,0 +1,46 @@
+package adminlockouts
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type LockoutStorage interface {
+	LoginLockouts() ([]*models.LoginLockout, error)
+}
+
+// New lists the usernames and the client ips whose login attempts are locked out.
+func New(log *slog.Logger, lockoutStorage LockoutStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.lockouts.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		lockouts, err := lockoutStorage.LoginLockouts()
+		if err != nil {
+			log.Error("failed to get the login lockouts", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		lockoutsResponse := make([]*types.LoginLockoutView, 0, len(lockouts))
+		for _, lockout := range lockouts {
+			lockoutsResponse = append(lockoutsResponse, types.NewLoginLockout(lockout))
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Lockouts: lockoutsResponse,
+				Size:     len(lockoutsResponse),
+			},
+		})
+	})
+}
@@ -1,17 +1,31 @@
 package login
 
-import "github.com/guluzadehh/go_chat/internal/lib/api"
+import (
+	"log/slog"
+
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+)
 
 type Request struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", r.Username),
+	)
+}
+
 type Response struct {
 	api.Response
 	Data `json:"data"`
 }
 
+// Data holds the access token, or the challenge to submit with the second factor
+// when the user has enabled two-factor authentication.
 type Data struct {
-	Token string `json:"token"`
+	Token     string `json:"token,omitempty"`
+	Challenge string `json:"challenge,omitempty"`
 }
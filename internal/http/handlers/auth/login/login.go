@@ -10,7 +10,9 @@ import (
 	"github.com/guluzadehh/go_chat/internal/lib/api"
 	"github.com/guluzadehh/go_chat/internal/lib/auth"
 	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/session"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
 	"github.com/guluzadehh/go_chat/internal/storage"
@@ -20,7 +22,7 @@ type LoginStorage interface {
 	UserByUsername(username string) (*models.User, error)
 }
 
-func New(log *slog.Logger, config *config.Config, loginStorage LoginStorage) http.Handler {
+func New(log *slog.Logger, config *config.Config, limiter *loginlimit.Limiter, loginStorage LoginStorage, tokenStorage session.TokenStorage) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.auth.login.New"
 
@@ -32,57 +34,70 @@ func New(log *slog.Logger, config *config.Config, loginStorage LoginStorage) htt
 			return
 		}
 
-		user, err := loginStorage.UserByUsername(req.Username)
-		if errors.Is(err, storage.UserNotFound) {
-			log.Info(err.Error(), slog.String("username", req.Username))
-			render.JSON(w, http.StatusUnauthorized, api.Err("invalid credentials."))
-			return
-		}
+		ip := api.ClientIP(r)
+
+		var user *models.User
+		ok, lockout, err := limiter.Attempt(req.Username, ip, func() (bool, error) {
+			found, err := loginStorage.UserByUsername(req.Username)
+			if err != nil && !errors.Is(err, storage.UserNotFound) {
+				return false, err
+			}
+			user = found
+
+			if user == nil {
+				auth.CheckPasswordHash(auth.DummyPasswordHash, req.Password)
+				return false, nil
+			}
+
+			return auth.CheckPasswordHash(user.Password, req.Password), nil
+		})
 		if err != nil {
-			log.Error("failed to get user by username from storage", sl.Err(err))
+			log.Error("failed to check the credentials", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 			return
 		}
-
-		if !auth.CheckPasswordHash(user.Password, req.Password) {
-			log.Info("invalid credentials", slog.String("username", req.Username), slog.String("password", req.Password))
+		if lockout > 0 {
+			log.Warn("login is locked out", slog.String("username", req.Username), slog.String("ip", ip), slog.Duration("lockout", lockout))
+			loginlimit.TooManyAttempts(w, lockout)
+			return
+		}
+		if !ok {
+			log.Info("failed login attempt", slog.String("username", req.Username), slog.String("ip", ip), slog.Bool("user_exists", user != nil))
 			render.JSON(w, http.StatusUnauthorized, api.Err("invalid credentials"))
 			return
 		}
 
-		access, err := jwt.AccessToken(user.Username, config)
-		if err != nil {
-			log.Error("can't create jwt access token", slog.String("username", user.Username), sl.Err(err))
-			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+		// The failed attempts of users with a second factor are kept until it is passed
+		// in logintotp, so that the password alone doesn't reset the lockout of the codes.
+		if user.TotpEnabled {
+			challenge, err := jwt.ChallengeToken(user, config)
+			if err != nil {
+				log.Error("can't create jwt challenge token", sl.User(user), sl.Err(err))
+				render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+				return
+			}
+			log.Info("second factor is required", sl.User(user))
+
+			render.JSON(w, http.StatusOK, Response{
+				Response: api.Ok(),
+				Data: Data{
+					Challenge: challenge,
+				},
+			})
 			return
 		}
-		log.Info("access token have been created", slog.String("username", user.Username))
 
-		refresh, err := jwt.RefreshToken(user.Username, config)
-		if err != nil {
-			log.Error("can't create jwt refresh token", slog.String("username", user.Username), sl.Err(err))
-			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
-			return
+		if err := limiter.Succeed(user.Username); err != nil {
+			log.Error("failed to reset the failed login attempts", sl.User(user), sl.Err(err))
 		}
-		log.Info("refresh token have been created", slog.String("username", user.Username))
 
-		encoded, err := auth.Encrypt(refresh, []byte(config.JWT.Refresh.EncryptSecretKey))
+		access, err := session.Start(w, config, tokenStorage, user)
 		if err != nil {
-			log.Error("failed to encrypt token", sl.Err(err))
+			log.Error("failed to start the session", sl.User(user), sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 			return
 		}
-		log.Info("encrypted the refresh token")
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     config.JWT.Refresh.CookieName,
-			Value:    encoded,
-			SameSite: http.SameSiteNoneMode,
-			Path:     "/api/refresh",
-			HttpOnly: true,
-			MaxAge:   int(config.JWT.Refresh.Expire.Seconds()),
-		})
-		log.Info("refresh cookie has been set", slog.String("refresh_cookie_name", config.JWT.Refresh.CookieName))
+		log.Info("user has logged in", sl.User(user))
 
 		render.JSON(w, http.StatusOK, Response{
 			Response: api.Ok(),
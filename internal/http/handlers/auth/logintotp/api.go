@@ -0,0 +1,31 @@
+package logintotp
+
+import (
+	"log/slog"
+
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+)
+
+// Request completes the login with either a code of the authenticator app or a recovery code.
+type Request struct {
+	Challenge    string `json:"challenge" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LogValue keeps the codes out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("has_code", r.Code != ""),
+		slog.Bool("has_recovery_code", r.RecoveryCode != ""),
+	)
+}
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,132 @@
+package logintotp
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/session"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/lib/totp"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+	UseRecoveryCode(userId int64, hash string) error
+}
+
+// New is the second step of the login of users with two-factor authentication, it
+// exchanges the challenge from login.New and a valid code for the access token.
+func New(
+	log *slog.Logger,
+	config *config.Config,
+	limiter *loginlimit.Limiter,
+	userStorage UserStorage,
+	stepStorage totp.StepStorage,
+	tokenStorage session.TokenStorage,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.logintotp.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var req Request
+		err := api.DecodeBody(log, w, r, &req)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		challenge, err := jwt.Verify(req.Challenge, jwt.TypeChallenge, config)
+		if err != nil {
+			log.Info("challenge token is invalid", sl.Err(err))
+			render.JSON(w, http.StatusUnauthorized, api.Err("challenge is invalid"))
+			return
+		}
+
+		user, err := userStorage.UserById(challenge.UserId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user of the challenge doesn't exist", slog.Int64("user_id", challenge.UserId))
+			render.JSON(w, http.StatusUnauthorized, api.Err("challenge is invalid"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get user by id from storage", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if !user.TotpEnabled {
+			log.Info("two-factor authentication of the challenge user is disabled", sl.User(user))
+			render.JSON(w, http.StatusUnauthorized, api.Err("challenge is invalid"))
+			return
+		}
+
+		ip := api.ClientIP(r)
+
+		ok, lockout, err := limiter.Attempt(user.Username, ip, func() (bool, error) {
+			if req.Code != "" {
+				return totp.Verify(stepStorage, user, req.Code)
+			}
+
+			err := userStorage.UseRecoveryCode(user.Id, totp.HashRecoveryCode(req.RecoveryCode))
+			if errors.Is(err, storage.RecoveryCodeNotFound) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+		if err != nil {
+			log.Error("failed to check the second factor", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if lockout > 0 {
+			log.Warn("login is locked out", sl.User(user), slog.String("ip", ip), slog.Duration("lockout", lockout))
+			loginlimit.TooManyAttempts(w, lockout)
+			return
+		}
+		if !ok {
+			log.Info("failed second factor attempt", sl.User(user), slog.String("ip", ip))
+			render.JSON(w, http.StatusUnauthorized, api.Err("invalid code"))
+			return
+		}
+
+		if req.Code == "" {
+			log.Warn("recovery code has been used", sl.User(user))
+		}
+
+		if err := limiter.Succeed(user.Username); err != nil {
+			log.Error("failed to reset the failed login attempts", sl.User(user), sl.Err(err))
+		}
+
+		access, err := session.Start(w, config, tokenStorage, user)
+		if err != nil {
+			log.Error("failed to start the session", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("user has logged in", sl.User(user))
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Token: access,
+			},
+		})
+	})
+}
@@ -7,26 +7,56 @@ import (
 	"github.com/guluzadehh/go_chat/internal/config"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
 	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 )
 
-func New(log *slog.Logger, config *config.Config) http.Handler {
+type TokenStorage interface {
+	RevokeRefreshFamily(family string) error
+}
+
+func New(log *slog.Logger, config *config.Config, tokenStorage TokenStorage) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.auth.logout.New"
 
 		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
 
-		http.SetCookie(w, &http.Cookie{
-			Name:     config.JWT.Refresh.CookieName,
-			Value:    "",
-			SameSite: http.SameSiteNoneMode,
-			Path:     "/api/refresh",
-			HttpOnly: true,
-			MaxAge:   -1,
-		})
+		if cookie, err := r.Cookie(config.JWT.Refresh.CookieName); err == nil {
+			revokeRefreshToken(log, config, tokenStorage, cookie.Value)
+		}
+
+		auth.ClearRefreshCookie(w, config)
 		log.Info("refresh cookie has been deleted", slog.String("refresh_cookie_name", config.JWT.Refresh.CookieName))
 
 		render.JSON(w, http.StatusOK, api.Ok())
 	})
 }
+
+// revokeRefreshToken revokes the family of the refresh token, invalid tokens are ignored
+// since they can't be used anyway.
+func revokeRefreshToken(log *slog.Logger, config *config.Config, tokenStorage TokenStorage, encrypted string) {
+	refreshStr, err := auth.Decrypt(encrypted, []byte(config.JWT.Refresh.EncryptSecretKey))
+	if err != nil {
+		log.Info("failed to decrypt token", sl.Err(err))
+		return
+	}
+
+	refresh, err := jwt.Verify(refreshStr, jwt.TypeRefresh, config)
+	if err != nil {
+		log.Info("refresh token is invalid", sl.Err(err))
+		return
+	}
+
+	if refresh.Family == "" {
+		log.Info("refresh token isn't tracked")
+		return
+	}
+
+	if err := tokenStorage.RevokeRefreshFamily(refresh.Family); err != nil {
+		log.Error("failed to revoke the refresh token", sl.Err(err))
+		return
+	}
+	log.Info("refresh token has been revoked", slog.String("family", refresh.Family))
+}
@@ -0,0 +1,46 @@
+package logoutall
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+)
+
+type TokenStorage interface {
+	RevokeUserTokens(userId int64) (int64, error)
+}
+
+// New invalidates every access and refresh token of the user and closes its chat connections.
+func New(log *slog.Logger, config *config.Config, hub *roomchat.Hub, tokenStorage TokenStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.logoutall.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		user := authmdw.User(r)
+
+		if _, err := tokenStorage.RevokeUserTokens(user.Id); err != nil {
+			log.Error("failed to revoke the tokens of the user", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("tokens of the user have been revoked", sl.User(user))
+
+		if err := hub.DisconnectUser(user.Id); err != nil {
+			log.Error("failed to disconnect the user from the chats", sl.User(user), sl.Err(err))
+		}
+
+		auth.ClearRefreshCookie(w, config)
+		log.Info("refresh cookie has been deleted", slog.String("refresh_cookie_name", config.JWT.Refresh.CookieName))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
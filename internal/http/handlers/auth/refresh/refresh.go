@@ -1,9 +1,11 @@
 package refresh
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/guluzadehh/go_chat/internal/config"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
 	"github.com/guluzadehh/go_chat/internal/lib/api"
@@ -11,9 +13,23 @@ import (
 	"github.com/guluzadehh/go_chat/internal/lib/jwt"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
 )
 
-func New(log *slog.Logger, config *config.Config) http.Handler {
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+}
+
+type TokenStorage interface {
+	RotateRefreshToken(family, jti, newJti string) error
+	RevokeRefreshFamily(family string) error
+	TokenVersion(userId int64) (int64, error)
+}
+
+// New issues a new access token and rotates the refresh token, the used refresh token
+// can't be used again.
+func New(log *slog.Logger, config *config.Config, userStorage UserStorage, tokenStorage TokenStorage) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.auth.refresh.New"
 
@@ -34,28 +50,97 @@ func New(log *slog.Logger, config *config.Config) http.Handler {
 		}
 		log.Info("refresh token decrypted")
 
-		refresh, err := jwt.Verify(refreshStr, config)
+		refresh, err := jwt.Verify(refreshStr, jwt.TypeRefresh, config)
 		if err != nil {
-			log.Info("refresh token is invalid", slog.String("invalid_refresh_token", refreshStr), sl.Err(err))
+			log.Info("refresh token is invalid", sl.Err(err))
 			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
 			return
 		}
 		log.Info("refresh token is verified")
 
-		username, err := refresh.Claims.GetSubject()
+		family, jti := refresh.Family, refresh.ID
+		if family == "" || jti == "" {
+			log.Info("refresh token isn't tracked", slog.Int64("user_id", refresh.UserId))
+			auth.ClearRefreshCookie(w, config)
+			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
+			return
+		}
+
+		user, err := userStorage.UserById(refresh.UserId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user of the refresh token doesn't exist", slog.Int64("user_id", refresh.UserId))
+			auth.ClearRefreshCookie(w, config)
+			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
+			return
+		}
+		if err != nil {
+			log.Error("failed to get user by id from storage", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		version, err := tokenStorage.TokenVersion(user.Id)
 		if err != nil {
-			log.Error("error while getting the subject from refresh token", sl.Err(err))
+			log.Error("failed to get the token version", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 			return
 		}
 
-		access, err := jwt.AccessToken(username, config)
+		if refresh.Version != version {
+			log.Info("refresh token has been revoked", sl.User(user), slog.String("family", family))
+			if err := tokenStorage.RevokeRefreshFamily(family); err != nil {
+				log.Error("failed to revoke the refresh token", sl.Err(err))
+			}
+			auth.ClearRefreshCookie(w, config)
+			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
+			return
+		}
+
+		newJti := uuid.NewString()
+
+		err = tokenStorage.RotateRefreshToken(family, jti, newJti)
+		if errors.Is(err, storage.RefreshTokenReused) {
+			log.Warn("refresh token reuse, the token family has been revoked", sl.User(user), slog.String("family", family))
+			auth.ClearRefreshCookie(w, config)
+			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
+			return
+		}
+		if errors.Is(err, storage.RefreshTokenNotFound) {
+			log.Info("refresh token has been revoked", sl.User(user), slog.String("family", family))
+			auth.ClearRefreshCookie(w, config)
+			render.JSON(w, http.StatusUnauthorized, refreshInvalidResponse())
+			return
+		}
+		if err != nil {
+			log.Error("failed to rotate the refresh token", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		newRefresh, err := jwt.RefreshToken(user, family, newJti, version, config)
+		if err != nil {
+			log.Error("can't create jwt refresh token", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		encoded, err := auth.Encrypt(newRefresh, []byte(config.JWT.Refresh.EncryptSecretKey))
+		if err != nil {
+			log.Error("failed to encrypt token", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		auth.SetRefreshCookie(w, encoded, config)
+		log.Info("refresh token has been rotated", sl.User(user))
+
+		access, err := jwt.AccessToken(user, version, config)
 		if err != nil {
 			log.Error("can't create jwt access token", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 			return
 		}
-		log.Info("access token have been created", slog.String("username", username))
+		log.Info("access token have been created", sl.User(user))
 
 		render.JSON(w, http.StatusOK, Response{
 			Response: api.Ok(),
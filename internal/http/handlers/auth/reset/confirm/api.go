@@ -0,0 +1,16 @@
+package resetconfirm
+
+import "log/slog"
+
+type Request struct {
+	Token        string `json:"token" validate:"required"`
+	Password     string `json:"password" validate:"required,min=5,max=72,passwordpattern"`
+	ConfPassword string `json:"conf_password" validate:"required,eqfield=Password"`
+}
+
+// LogValue keeps the token and the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("has_token", r.Token != ""),
+	)
+}
@@ -0,0 +1,101 @@
+package resetconfirm
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/lib/validators"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type UserStorage interface {
+	UpdatePassword(userId int64, password string) error
+}
+
+type TokenStorage interface {
+	TakePasswordReset(hash string) (int64, error)
+	RevokeUserTokens(userId int64) (int64, error)
+}
+
+// New sets a new password with a password reset token, every session of the user is revoked.
+func New(log *slog.Logger, hub *roomchat.Hub, userStorage UserStorage, tokenStorage TokenStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.reset.confirm.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		v.RegisterValidation("passwordpattern", validators.PasswordPatternValidator)
+
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		if len(body.Password) > auth.MaxPasswordLen {
+			log.Info("password is too long", slog.Int("length", len(body.Password)))
+			render.JSON(w, http.StatusBadRequest, api.Err("password is too long"))
+			return
+		}
+
+		userId, err := tokenStorage.TakePasswordReset(auth.HashToken(body.Token))
+		if errors.Is(err, storage.ResetTokenNotFound) {
+			log.Info("password reset token is invalid")
+			render.JSON(w, http.StatusBadRequest, api.Err("password reset token is invalid"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the password reset token", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		hashedPassword, err := auth.HashPassword(body.Password)
+		if err != nil {
+			log.Error("can't hash password", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		err = userStorage.UpdatePassword(userId, hashedPassword)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user of the password reset token doesn't exist", slog.Int64("user_id", userId))
+			render.JSON(w, http.StatusBadRequest, api.Err("password reset token is invalid"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update the password", slog.Int64("user_id", userId), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("password has been reset", slog.Int64("user_id", userId))
+
+		if _, err := tokenStorage.RevokeUserTokens(userId); err != nil {
+			log.Error("failed to revoke the tokens of the user", slog.Int64("user_id", userId), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := hub.DisconnectUser(userId); err != nil {
+			log.Error("failed to disconnect the user from the chats", slog.Int64("user_id", userId), sl.Err(err))
+		}
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
@@ -0,0 +1,5 @@
+package resetrequest
+
+type Request struct {
+	Username string `json:"username" validate:"required"`
+}
@@ -0,0 +1,112 @@
+package resetrequest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
+	"github.com/guluzadehh/go_chat/internal/lib/notify"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type UserStorage interface {
+	UserByUsername(username string) (*models.User, error)
+}
+
+type ResetStorage interface {
+	SavePasswordReset(hash string, userId int64, ttl time.Duration) error
+}
+
+// New sends a password reset token to the user through the notifier. It responds the same
+// whether or not the user exists, so that it can't be used to look usernames up. Every
+// request counts against the limits of the username and the client ip.
+func New(log *slog.Logger, config *config.Config, limiter *loginlimit.Limiter, notifier notify.Notifier, userStorage UserStorage, resetStorage ResetStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.reset.request.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		ip := api.ClientIP(r)
+
+		lockout, err := limiter.Check(body.Username, ip)
+		if err != nil {
+			log.Error("failed to check the password reset lockout", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if lockout > 0 {
+			log.Info("locked out password reset request", slog.String("username", body.Username), slog.String("ip", ip))
+			loginlimit.TooManyAttempts(w, lockout)
+			return
+		}
+
+		if _, err := limiter.Fail(body.Username, ip); err != nil {
+			log.Error("failed to record the password reset request", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user, err := userStorage.UserByUsername(body.Username)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("password reset for a user that doesn't exist", slog.String("username", body.Username))
+			render.JSON(w, http.StatusAccepted, api.Ok())
+			return
+		}
+		if err != nil {
+			log.Error("failed to get user by username from storage", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		token, err := auth.GenerateToken()
+		if err != nil {
+			log.Error("can't generate password reset token", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		expire := config.Account.PasswordResetExpire
+		if err := resetStorage.SavePasswordReset(auth.HashToken(token), user.Id, expire); err != nil {
+			log.Error("failed to save the password reset token", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		err = notifier.Notify(user, notify.Message{
+			Subject: "Password reset",
+			Body:    fmt.Sprintf("Use this token to reset your password: %s\nIt expires in %s.", token, expire),
+		})
+		if err != nil {
+			log.Error("failed to send the password reset token", sl.User(user), sl.Err(err))
+		} else {
+			log.Info("password reset token has been sent", sl.User(user))
+		}
+
+		render.JSON(w, http.StatusAccepted, api.Ok())
+	})
+}
@@ -1,14 +1,25 @@
 package signup
 
 import (
+	"log/slog"
+
 	"github.com/guluzadehh/go_chat/internal/lib/api"
 	"github.com/guluzadehh/go_chat/internal/types"
 )
 
 type Request struct {
 	Username     string `json:"username" validate:"required,max=16"`
-	Password     string `json:"password" validate:"required,min=5,passwordpattern"`
+	Password     string `json:"password" validate:"required,min=5,max=72,passwordpattern"`
 	ConfPassword string `json:"conf_password" validate:"required,eqfield=Password"`
+	Email        string `json:"email" validate:"omitempty,email,max=255"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", r.Username),
+		slog.String("email", r.Email),
+	)
 }
 
 type Response struct {
@@ -18,7 +18,7 @@ import (
 )
 
 type SignupStorage interface {
-	CreateUser(username, password string) (*models.User, error)
+	CreateUser(username, password, email string) (*models.User, error)
 }
 
 func New(log *slog.Logger, signupStorage SignupStorage) http.Handler {
@@ -43,14 +43,20 @@ func New(log *slog.Logger, signupStorage SignupStorage) http.Handler {
 			return
 		}
 
+		if len(body.Password) > auth.MaxPasswordLen {
+			log.Info("password is too long", slog.Int("length", len(body.Password)))
+			render.JSON(w, http.StatusBadRequest, api.Err("password is too long"))
+			return
+		}
+
 		hashedPassword, err := auth.HashPassword(body.Password)
 		if err != nil {
-			log.Error("can't hash password", slog.String("password", body.Password), sl.Err(err))
+			log.Error("can't hash password", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 			return
 		}
 
-		user, err := signupStorage.CreateUser(body.Username, hashedPassword)
+		user, err := signupStorage.CreateUser(body.Username, hashedPassword, body.Email)
 		if errors.Is(err, storage.UsernameExists) {
 			log.Info(err.Error(), slog.String("username", body.Username))
 			render.JSON(w, http.StatusConflict,
@@ -63,6 +69,18 @@ func New(log *slog.Logger, signupStorage SignupStorage) http.Handler {
 			)
 			return
 		}
+		if errors.Is(err, storage.EmailExists) {
+			log.Info(err.Error(), slog.String("email", body.Email))
+			render.JSON(w, http.StatusConflict,
+				api.ErrD("email exists", []api.ErrDetail{
+					{
+						Field:   "email",
+						Message: "email is already taken",
+					},
+				}),
+			)
+			return
+		}
 		if err != nil {
 			log.Info("failed to create user", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
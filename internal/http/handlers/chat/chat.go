@@ -3,6 +3,7 @@ package chat
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -12,7 +13,10 @@ import (
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
 	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
 	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
@@ -21,16 +25,23 @@ import (
 
 type RoomStorage interface {
 	RoomByUuid(uuid string) (*models.Room, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	AddRoomMember(uuid string, userId int64) error
+	SetRoomPassword(uuid, password string) error
+	IsBanned(uuid string, userId int64) (bool, error)
+	InviteById(roomUuid, id string) (*models.Invite, error)
+	RedeemInvite(roomUuid, id string) (*models.Invite, error)
 }
 
-func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage) http.Handler {
+// invalidInvite is returned for invites that can't be redeemed by the user.
+var invalidInvite = errors.New("invalid invite")
+
+func New(log *slog.Logger, config *config.Config, hub *roomchat.Hub, roomStorage RoomStorage) http.Handler {
 	var upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 	}
 
-	hub := roomchat.NewHub(config)
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.chat.New"
 
@@ -38,6 +49,13 @@ func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage) http.
 
 		roomUuid := mux.Vars(r)["room_uuid"]
 
+		since, err := api.QueryInt(r, "since", 0)
+		if err != nil || since < 0 {
+			log.Info("invalid since parameter", slog.String("since", r.URL.Query().Get("since")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid since parameter"))
+			return
+		}
+
 		room, err := roomStorage.RoomByUuid(roomUuid)
 		if err != nil {
 			if errors.Is(err, storage.RoomNotFound) {
@@ -51,17 +69,37 @@ func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage) http.
 			return
 		}
 
+		user := authmdw.User(r)
+
+		banned, err := roomStorage.IsBanned(room.Uuid, user.Id)
+		if err != nil {
+			log.Error("failed to check the ban", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if banned {
+			log.Info("banned user join attempt", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are banned from this room"))
+			return
+		}
+
+		if room.IsDirect() && !room.IsParticipant(user.Id) {
+			log.Info("unauthorized attempt to join a direct conversation", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Error("failed to upgrade connection", sl.Err(err))
 			return
 		}
 
-		user := authmdw.User(r)
-
 		if room.IsPrivate() {
 			var msg struct {
 				Password string `json:"password"`
+				Invite   string `json:"invite"`
+				Since    int64  `json:"since"`
 			}
 
 			msgType, rcv, err := conn.ReadMessage()
@@ -82,24 +120,67 @@ func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage) http.
 			}
 
 			if err := json.Unmarshal(rcv, &msg); err != nil {
-				log.Info("failed to read password", slog.String("rcv", string(rcv)), sl.Err(err))
+				log.Info("failed to read password", sl.Err(err))
 				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInvalidFramePayloadData, failMsg))
 				conn.Close()
 				return
 			}
 
-			if msg.Password != room.Password {
-				log.Warn("invalid password for room", slog.Any("room", room), sl.User(user), slog.String("password", msg.Password))
-				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "wrong password"))
+			granted, err := roomaccess.Granted(roomStorage, room, user)
+			if err != nil {
+				log.Error("failed to check room membership", sl.Err(err))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, failMsg))
 				conn.Close()
 				return
 			}
 
+			if !granted {
+				if msg.Invite != "" {
+					err := redeemInvite(config, roomStorage, room, user, msg.Invite)
+					if errors.Is(err, invalidInvite) {
+						log.Warn("invalid invite for room", slog.Any("room", room), sl.User(user), sl.Err(err))
+						conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "invalid invite"))
+						conn.Close()
+						return
+					}
+					if err != nil {
+						log.Error("failed to redeem the invite", sl.Err(err))
+						conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, failMsg))
+						conn.Close()
+						return
+					}
+				} else {
+					if !roomaccess.CheckPassword(room, msg.Password) {
+						log.Warn("invalid password for room", slog.Any("room", room), sl.User(user))
+						conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "wrong password"))
+						conn.Close()
+						return
+					}
+
+					if roomaccess.NeedsRehash(room) {
+						rehashPassword(log, roomStorage, room, msg.Password)
+					}
+				}
+
+				if err := roomStorage.AddRoomMember(room.Uuid, user.Id); err != nil {
+					log.Error("failed to save room membership", sl.Err(err))
+				}
+			}
+
+			if msg.Since > 0 {
+				since = msg.Since
+			}
+
 			log.Info("gained access to the room", sl.User(user), slog.Any("room", room))
+		} else if !room.IsDirect() && room.OwnerId != user.Id {
+			// Users of public rooms are recorded as members as well, so that the room
+			// is indexed for them and can be handed over to them with its owner gone.
+			if err := roomStorage.AddRoomMember(room.Uuid, user.Id); err != nil {
+				log.Error("failed to save room membership", sl.Err(err))
+			}
 		}
 
-		chatRoom := hub.GetOrCreateRoom(room)
-		member, err := chatRoom.NewMember(conn, user)
+		member, err := hub.Join(room, conn, user, since)
 		if errors.Is(err, roomchat.RoomIsFull) {
 			log.Info("full room join attempt", sl.User(user), slog.Any("room", room))
 
@@ -108,8 +189,65 @@ func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage) http.
 
 			return
 		}
+		if err != nil {
+			log.Error("failed to join the room", sl.User(user), slog.Any("room", room), slog.Int64("since", since), sl.Err(err))
+			conn.Close()
+			return
+		}
 		log.Info("member is created", sl.User(user), slog.Any("room", room))
 
 		go member.ReadPump()
 	})
 }
+
+// redeemInvite counts a use of the invite token if it was issued for the room and the user.
+func redeemInvite(config *config.Config, roomStorage RoomStorage, room *models.Room, user *models.User, token string) error {
+	roomUuid, inviteId, err := jwt.VerifyInvite(token, config)
+	if err != nil {
+		return fmt.Errorf("%w: %w", invalidInvite, err)
+	}
+	if roomUuid != room.Uuid {
+		return fmt.Errorf("%w: issued for another room", invalidInvite)
+	}
+
+	invite, err := roomStorage.InviteById(room.Uuid, inviteId)
+	if errors.Is(err, storage.InviteNotFound) {
+		return fmt.Errorf("%w: %w", invalidInvite, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if invite.Username != "" && invite.Username != user.Username {
+		return fmt.Errorf("%w: issued for another user", invalidInvite)
+	}
+
+	_, err = roomStorage.RedeemInvite(room.Uuid, inviteId)
+	if errors.Is(err, storage.InviteNotFound) || errors.Is(err, storage.InviteExhausted) {
+		return fmt.Errorf("%w: %w", invalidInvite, err)
+	}
+
+	return err
+}
+
+// rehashPassword replaces the plaintext password of a room created before passwords were hashed.
+// A password too long for bcrypt is kept as it is.
+func rehashPassword(log *slog.Logger, roomStorage RoomStorage, room *models.Room, password string) {
+	if len(password) > auth.MaxPasswordLen {
+		log.Warn("room password is too long to be rehashed", slog.Any("room", room))
+		return
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Error("can't hash room password", sl.Err(err))
+		return
+	}
+
+	if err := roomStorage.SetRoomPassword(room.Uuid, hash); err != nil {
+		log.Error("failed to rehash room password", slog.Any("room", room), sl.Err(err))
+		return
+	}
+
+	log.Info("room password has been rehashed", slog.Any("room", room))
+}
@@ -0,0 +1,16 @@
+package dmlist
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Directs []*types.DirectView `json:"directs"`
+	Size    int                 `json:"size"`
+}
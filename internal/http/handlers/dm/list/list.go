@@ -0,0 +1,100 @@
+package dmlist
+
+import (
+	"cmp"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type RoomStorage interface {
+	DirectRooms(userId int64) ([]*models.Room, error)
+}
+
+type UserStorage interface {
+	UsersWithIds(ids []int64) (map[int64]*models.User, error)
+}
+
+type MessageStorage interface {
+	LastMessages(roomUuids []string) (map[string]*models.Message, error)
+	UnreadCounts(userId int64, roomUuids []string) (map[string]int, error)
+}
+
+// New lists the direct conversations of the user, the most recently active first.
+func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage, messageStorage MessageStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.dm.list.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		user := authmdw.User(r)
+
+		rooms, err := roomStorage.DirectRooms(user.Id)
+		if err != nil {
+			log.Error("failed to get the direct conversations", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		peer_ids := make([]int64, 0, len(rooms))
+		uuids := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			peer_ids = append(peer_ids, room.Peer(user.Id))
+			uuids = append(uuids, room.Uuid)
+		}
+
+		peers, err := userStorage.UsersWithIds(peer_ids)
+		if err != nil {
+			log.Error("failed to get the users of direct conversations", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		last, err := messageStorage.LastMessages(uuids)
+		if err != nil {
+			log.Error("failed to get the last messages", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		unread, err := messageStorage.UnreadCounts(user.Id, uuids)
+		if err != nil {
+			log.Error("failed to get the unread counts", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		slices.SortFunc(rooms, func(a, b *models.Room) int {
+			return cmp.Compare(lastId(last[b.Uuid]), lastId(last[a.Uuid]))
+		})
+
+		directs := make([]*types.DirectView, 0, len(rooms))
+		for _, room := range rooms {
+			directs = append(directs, types.NewDirect(room, peers[room.Peer(user.Id)], last[room.Uuid], unread[room.Uuid]))
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Directs: directs,
+				Size:    len(directs),
+			},
+		})
+	})
+}
+
+func lastId(m *models.Message) int64 {
+	if m == nil {
+		return 0
+	}
+
+	return m.Id
+}
@@ -0,0 +1,15 @@
+package dmopen
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	Direct *types.DirectView `json:"direct"`
+}
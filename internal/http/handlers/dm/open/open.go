@@ -0,0 +1,93 @@
+package dmopen
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type RoomStorage interface {
+	DirectRoom(userId, peerId int64) (*models.Room, error)
+}
+
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+}
+
+type MessageStorage interface {
+	LastMessages(roomUuids []string) (map[string]*models.Message, error)
+	UnreadCounts(userId int64, roomUuids []string) (map[string]int, error)
+}
+
+// New finds or creates the direct conversation with the user, it is joined through the
+// chat endpoint of the room.
+func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage, messageStorage MessageStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.dm.open.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		peerId, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+		if err != nil {
+			log.Info("invalid user id", slog.String("user_id", mux.Vars(r)["user_id"]))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid user id"))
+			return
+		}
+
+		user := authmdw.User(r)
+		if peerId == user.Id {
+			log.Info("direct conversation with oneself", sl.User(user))
+			render.JSON(w, http.StatusBadRequest, api.Err("you can't message yourself"))
+			return
+		}
+
+		peer, err := userStorage.UserById(peerId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user doesn't exist", slog.Int64("user_id", peerId))
+			render.JSON(w, http.StatusNotFound, api.Err("user is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the user", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		room, err := roomStorage.DirectRoom(user.Id, peer.Id)
+		if err != nil {
+			log.Error("failed to get the direct conversation", sl.User(user), slog.Int64("peer_id", peer.Id), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		last, err := messageStorage.LastMessages([]string{room.Uuid})
+		if err != nil {
+			log.Error("failed to get the last message", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		unread, err := messageStorage.UnreadCounts(user.Id, []string{room.Uuid})
+		if err != nil {
+			log.Error("failed to get the unread count", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data:     Data{Direct: types.NewDirect(room, peer, last[room.Uuid], unread[room.Uuid])},
+		})
+	})
+}
@@ -0,0 +1,25 @@
+package jwks
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+)
+
+// New serves the public keys tokens are verified with, as a JSON Web Key Set.
+func New(log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.jwks.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		jwks := jwt.PublicKeys()
+		log.Debug("serving the key set", slog.Int("keys", len(jwks.Keys)))
+
+		render.JSON(w, http.StatusOK, jwks)
+	})
+}
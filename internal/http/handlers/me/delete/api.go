@@ -0,0 +1,12 @@
+package medelete
+
+import "log/slog"
+
+type Request struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue()
+}
@@ -0,0 +1,200 @@
+package medelete
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// Policies for the rooms owned by a deleted user.
+const (
+	OwnedRoomsDelete   = "delete"
+	OwnedRoomsTransfer = "transfer"
+)
+
+type UserStorage interface {
+	DeleteUser(userId int64) error
+	UsersWithIds(ids []int64) (map[int64]*models.User, error)
+}
+
+type RoomStorage interface {
+	UserRooms(userId int64) ([]*models.Room, error)
+	DeleteRoom(uuid string) error
+	TransferRoom(uuid string, ownerId int64) error
+	RoomModerators(uuid string) ([]int64, error)
+	RoomMembers(uuid string) ([]int64, error)
+	PurgeRoomUser(uuid string, userId int64) error
+}
+
+type TokenStorage interface {
+	RevokeUserTokens(userId int64) (int64, error)
+}
+
+// New deletes the account of the user. The rooms the user owns are deleted or transferred
+// depending on config.Account.OwnedRooms, they are released before the user is deleted so
+// that a failed deletion can be retried.
+func New(log *slog.Logger, config *config.Config, hub *roomchat.Hub, userStorage UserStorage, roomStorage RoomStorage, tokenStorage TokenStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.delete.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		user := authmdw.User(r)
+
+		if !auth.CheckPasswordHash(user.Password, body.Password) {
+			log.Info("password is wrong", sl.User(user))
+			render.JSON(w, http.StatusForbidden, api.Err("password is wrong"))
+			return
+		}
+
+		if err := releaseRooms(log, config.Account.OwnedRooms, userStorage, roomStorage, user); err != nil {
+			log.Error("failed to release the rooms of the user", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := userStorage.DeleteUser(user.Id); err != nil {
+			log.Error("failed to delete the user", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("user has been deleted", sl.User(user))
+
+		if _, err := tokenStorage.RevokeUserTokens(user.Id); err != nil {
+			log.Error("failed to revoke the tokens of the user", sl.User(user), sl.Err(err))
+		}
+
+		if err := hub.DisconnectUser(user.Id); err != nil {
+			log.Error("failed to disconnect the user from the chats", sl.User(user), sl.Err(err))
+		}
+
+		auth.ClearRefreshCookie(w, config)
+
+		render.JSON(w, http.StatusNoContent, api.Ok())
+	})
+}
+
+// releaseRooms deletes the rooms owned by the user or hands them over to a successor.
+// A room without a successor is deleted, and so are the direct conversations of the user.
+// The user is removed from every room it holds a membership, role, ban or mute in, each room
+// leaves the index of the user once released, so a failed release resumes where it stopped.
+func releaseRooms(log *slog.Logger, policy string, userStorage UserStorage, roomStorage RoomStorage, user *models.User) error {
+	rooms, err := roomStorage.UserRooms(user.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, room := range rooms {
+		if err := releaseRoom(log, policy, userStorage, roomStorage, room, user); err != nil {
+			return fmt.Errorf("%s: %w", room.Uuid, err)
+		}
+
+		if err := roomStorage.PurgeRoomUser(room.Uuid, user.Id); err != nil {
+			return fmt.Errorf("%s: %w", room.Uuid, err)
+		}
+	}
+
+	return nil
+}
+
+// releaseRoom transfers or deletes the room if the user owns it.
+func releaseRoom(log *slog.Logger, policy string, userStorage UserStorage, roomStorage RoomStorage, room *models.Room, user *models.User) error {
+	owned := room.OwnerId == user.Id
+	if room.IsDirect() {
+		owned = room.IsParticipant(user.Id)
+	}
+	if !owned {
+		return nil
+	}
+
+	if policy == OwnedRoomsTransfer && !room.IsDirect() {
+		successor, err := successor(userStorage, roomStorage, room, user)
+		if err != nil {
+			return err
+		}
+
+		if successor != 0 {
+			if err := roomStorage.TransferRoom(room.Uuid, successor); err != nil {
+				return err
+			}
+			log.Info("room has been transferred", slog.Any("room", room), slog.Int64("owner_id", successor))
+			return nil
+		}
+	}
+
+	if err := roomStorage.DeleteRoom(room.Uuid); err != nil && !errors.Is(err, storage.RoomNotFound) {
+		return err
+	}
+	log.Info("room has been deleted", slog.Any("room", room))
+
+	return nil
+}
+
+// successor picks the longest standing moderator of the room, or member if there are no
+// moderators, it returns 0 if the room has neither.
+func successor(userStorage UserStorage, roomStorage RoomStorage, room *models.Room, user *models.User) (int64, error) {
+	moderators, err := roomStorage.RoomModerators(room.Uuid)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := oldest(userStorage, moderators, user.Id)
+	if err != nil || id != 0 {
+		return id, err
+	}
+
+	members, err := roomStorage.RoomMembers(room.Uuid)
+	if err != nil {
+		return 0, err
+	}
+
+	return oldest(userStorage, members, user.Id)
+}
+
+// oldest returns the first of the ids, ordered by grant time, other than exclude whose user
+// still exists.
+func oldest(userStorage UserStorage, ids []int64, exclude int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	users, err := userStorage.UsersWithIds(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if _, ok := users[id]; ok && id != exclude {
+			return id, nil
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,15 @@
+package meget
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	User *types.MeView `json:"user"`
+}
@@ -0,0 +1,20 @@
+package meget
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+func New(log *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data:     Data{User: types.NewMe(authmdw.User(r))},
+		})
+	})
+}
@@ -0,0 +1,18 @@
+package mentionlist
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Mentions    []*types.MentionView `json:"mentions"`
+	Size        int                  `json:"size"`
+	UnreadCount int                  `json:"unread_count"`
+	NextBefore  int64                `json:"next_before,omitempty"`
+}
@@ -0,0 +1,95 @@
+package mentionlist
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+type MentionStorage interface {
+	Mentions(userId, before int64, limit int, unreadOnly bool) ([]*models.Mention, error)
+	UnreadMentionCount(userId int64) (int, error)
+}
+
+// New lists the mentions of the user, latest first. With unread=true only the mentions
+// the user hasn't read yet are listed.
+func New(log *slog.Logger, mentionStorage MentionStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.mentions.list.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		before, err := api.QueryInt(r, "before", 0)
+		if err != nil || before < 0 {
+			log.Info("invalid before parameter", slog.String("before", r.URL.Query().Get("before")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid before parameter"))
+			return
+		}
+
+		limit, err := api.QueryInt(r, "limit", defaultLimit)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			log.Info("invalid limit parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid limit parameter"))
+			return
+		}
+
+		var unreadOnly bool
+		if unread := r.URL.Query().Get("unread"); unread != "" {
+			unreadOnly, err = strconv.ParseBool(unread)
+			if err != nil {
+				log.Info("invalid unread parameter", slog.String("unread", unread))
+				render.JSON(w, http.StatusBadRequest, api.Err("invalid unread parameter"))
+				return
+			}
+		}
+
+		user := authmdw.User(r)
+
+		mentions, err := mentionStorage.Mentions(user.Id, before, int(limit), unreadOnly)
+		if err != nil {
+			log.Error("failed to get the mentions", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		unread, err := mentionStorage.UnreadMentionCount(user.Id)
+		if err != nil {
+			log.Error("failed to count unread mentions", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		views := make([]*types.MentionView, 0, len(mentions))
+		for _, mention := range mentions {
+			views = append(views, types.NewMention(mention))
+		}
+
+		var nextBefore int64
+		if len(mentions) == int(limit) {
+			nextBefore = mentions[len(mentions)-1].Message.Id
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Mentions:    views,
+				Size:        len(views),
+				UnreadCount: unread,
+				NextBefore:  nextBefore,
+			},
+		})
+	})
+}
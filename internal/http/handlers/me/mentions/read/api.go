@@ -0,0 +1,6 @@
+package mentionread
+
+// Request lists the messages whose mentions are read, every mention is read when empty.
+type Request struct {
+	MessageIds []int64 `json:"message_ids" validate:"max=100,dive,min=1"`
+}
@@ -0,0 +1,50 @@
+package mentionread
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+)
+
+type MentionStorage interface {
+	MarkMentionsRead(userId int64, messageIds []int64) error
+}
+
+func New(log *slog.Logger, mentionStorage MentionStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.mentions.read.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		user := authmdw.User(r)
+
+		if err := mentionStorage.MarkMentionsRead(user.Id, body.MessageIds); err != nil {
+			log.Error("failed to mark mentions as read", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("mentions have been read", sl.User(user), slog.Int("messages", len(body.MessageIds)))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
@@ -0,0 +1,27 @@
+package mepassword
+
+import (
+	"log/slog"
+
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+)
+
+type Request struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required,min=5,max=72,passwordpattern"`
+	ConfPassword    string `json:"conf_password" validate:"required,eqfield=Password"`
+}
+
+// LogValue keeps the passwords out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue()
+}
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,127 @@
+package mepassword
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/session"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/lib/validators"
+)
+
+type UserStorage interface {
+	UpdatePassword(userId int64, password string) error
+}
+
+type TokenStorage interface {
+	session.TokenStorage
+	RevokeUserTokens(userId int64) (int64, error)
+}
+
+// New changes the password of the user. The current password is checked under the login
+// limits, every other session is revoked and the current one gets fresh tokens.
+func New(log *slog.Logger, config *config.Config, hub *roomchat.Hub, limiter *loginlimit.Limiter, userStorage UserStorage, tokenStorage TokenStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.password.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		v.RegisterValidation("passwordpattern", validators.PasswordPatternValidator)
+
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		if len(body.Password) > auth.MaxPasswordLen {
+			log.Info("password is too long", slog.Int("length", len(body.Password)))
+			render.JSON(w, http.StatusBadRequest, api.Err("password is too long"))
+			return
+		}
+
+		user := authmdw.User(r)
+		ip := api.ClientIP(r)
+
+		ok, lockout, err := limiter.Attempt(user.Username, ip, func() (bool, error) {
+			return auth.CheckPasswordHash(user.Password, body.CurrentPassword), nil
+		})
+		if err != nil {
+			log.Error("failed to check the current password", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if lockout > 0 {
+			log.Warn("password attempts are locked out", sl.User(user), slog.String("ip", ip), slog.Duration("lockout", lockout))
+			loginlimit.TooManyAttempts(w, lockout)
+			return
+		}
+		if !ok {
+			log.Info("current password is wrong", sl.User(user), slog.String("ip", ip))
+			render.JSON(w, http.StatusForbidden, api.ErrD("password is wrong", []api.ErrDetail{
+				{
+					Field:   "current_password",
+					Message: "current password is wrong",
+				},
+			}))
+			return
+		}
+
+		if err := limiter.Succeed(user.Username); err != nil {
+			log.Error("failed to reset the failed login attempts", sl.User(user), sl.Err(err))
+		}
+
+		hashedPassword, err := auth.HashPassword(body.Password)
+		if err != nil {
+			log.Error("can't hash password", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := userStorage.UpdatePassword(user.Id, hashedPassword); err != nil {
+			log.Error("failed to update the password", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("password has been changed", sl.User(user))
+
+		if _, err := tokenStorage.RevokeUserTokens(user.Id); err != nil {
+			log.Error("failed to revoke the tokens of the user", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := hub.DisconnectUser(user.Id); err != nil {
+			log.Error("failed to disconnect the user from the chats", sl.User(user), sl.Err(err))
+		}
+
+		access, err := session.Start(w, config, tokenStorage, user)
+		if err != nil {
+			log.Error("failed to start a new session", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data:     Data{Token: access},
+		})
+	})
+}
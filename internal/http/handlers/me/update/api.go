@@ -0,0 +1,22 @@
+package meupdate
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+// Request changes only the fields that are present, an empty value clears the field.
+type Request struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=64"`
+	AvatarUrl   *string `json:"avatar_url" validate:"omitempty,http_url,max=2048"`
+	Bio         *string `json:"bio" validate:"omitempty,max=500"`
+}
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	User *types.MeView `json:"user"`
+}
@@ -0,0 +1,63 @@
+package meupdate
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type UserStorage interface {
+	UpdateProfile(userId int64, displayName, avatarUrl, bio string) error
+}
+
+func New(log *slog.Logger, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.update.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		user := *authmdw.User(r)
+		if body.DisplayName != nil {
+			user.DisplayName = *body.DisplayName
+		}
+		if body.AvatarUrl != nil {
+			user.AvatarUrl = *body.AvatarUrl
+		}
+		if body.Bio != nil {
+			user.Bio = *body.Bio
+		}
+
+		if err := userStorage.UpdateProfile(user.Id, user.DisplayName, user.AvatarUrl, user.Bio); err != nil {
+			log.Error("failed to update the profile", sl.User(&user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("profile has been updated", sl.User(&user))
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data:     Data{User: types.NewMe(&user)},
+		})
+	})
+}
@@ -0,0 +1,5 @@
+package roomban
+
+type Request struct {
+	UserId int64 `json:"user_id" validate:"required"`
+}
@@ -1,13 +1,23 @@
 package roomcreate
 
 import (
+	"log/slog"
+
 	"github.com/guluzadehh/go_chat/internal/lib/api"
 	"github.com/guluzadehh/go_chat/internal/types"
 )
 
 type Request struct {
 	Name     string `json:"name" validate:"required,max=20"`
-	Password string `json:"password"`
+	Password string `json:"password" validate:"max=72"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", r.Name),
+		slog.Bool("has_password", r.Password != ""),
+	)
 }
 
 type Response struct {
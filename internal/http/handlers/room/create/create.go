@@ -8,6 +8,7 @@ import (
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
 	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
@@ -38,9 +39,25 @@ func New(log *slog.Logger, roomStorage RoomStorage) http.Handler {
 			return
 		}
 
+		if len(body.Password) > auth.MaxPasswordLen {
+			log.Info("room password is too long", slog.Int("length", len(body.Password)))
+			render.JSON(w, http.StatusBadRequest, api.Err("password is too long"))
+			return
+		}
+
 		user := authmdw.User(r)
 
-		room, err := roomStorage.CreateRoom(body.Name, body.Password, user.Id)
+		password := body.Password
+		if password != "" {
+			password, err = auth.HashPassword(body.Password)
+			if err != nil {
+				log.Error("can't hash room password", sl.Err(err))
+				render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+				return
+			}
+		}
+
+		room, err := roomStorage.CreateRoom(body.Name, password, user.Id)
 		if err != nil {
 			log.Error("failed to create a room", sl.Err(err))
 			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
@@ -51,7 +68,7 @@ func New(log *slog.Logger, roomStorage RoomStorage) http.Handler {
 		render.JSON(w, http.StatusCreated, Response{
 			Response: api.Ok(),
 			Data: Data{
-				Room: types.NewRoom(room, user),
+				Room: types.NewRoom(room, user, 0, 0),
 			},
 		})
 	})
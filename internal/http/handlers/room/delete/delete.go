@@ -40,7 +40,7 @@ func New(log *slog.Logger, roomStorage RoomStorage) http.Handler {
 		}
 
 		user := authmdw.User(r)
-		if room.OwnerId != user.Id {
+		if room.OwnerId != user.Id || room.IsDirect() {
 			log.Info("unauthorized access to delete the room", sl.User(user), slog.Any("room", room))
 			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
 			return
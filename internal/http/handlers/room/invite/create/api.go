@@ -0,0 +1,23 @@
+package invitecreate
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+// Request creates an invite valid for ExpiresIn seconds, a zero MaxUses means unlimited uses.
+// The invite can only be redeemed by Username when it is set.
+type Request struct {
+	ExpiresIn int64  `json:"expires_in" validate:"required,min=60,max=2592000"`
+	MaxUses   int    `json:"max_uses" validate:"min=0"`
+	Username  string `json:"username"`
+}
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	Invite *types.InviteView `json:"invite"`
+}
@@ -0,0 +1,112 @@
+package invitecreate
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	CreateInvite(roomUuid string, createdBy int64, username string, maxUses int, expiresAt time.Time) (*models.Invite, error)
+}
+
+type UserStorage interface {
+	UserByUsername(username string) (*models.User, error)
+}
+
+// New lets the owner of a room create an invite to it.
+func New(log *slog.Logger, config *config.Config, roomStorage RoomStorage, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.invite.create.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+		if room.OwnerId != user.Id || room.IsDirect() {
+			log.Info("unauthorized attempt to create an invite", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		if body.Username != "" {
+			_, err := userStorage.UserByUsername(body.Username)
+			if errors.Is(err, storage.UserNotFound) {
+				log.Info("user doesn't exist", slog.String("username", body.Username))
+				render.JSON(w, http.StatusNotFound, api.Err("user is not found"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get the user", sl.Err(err))
+				render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+				return
+			}
+		}
+
+		expiresAt := time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+
+		invite, err := roomStorage.CreateInvite(room.Uuid, user.Id, body.Username, body.MaxUses, expiresAt)
+		if err != nil {
+			log.Error("failed to create an invite", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		token, err := jwt.InviteToken(room.Uuid, invite.Id, invite.ExpiresAt, config)
+		if err != nil {
+			log.Error("can't create an invite token", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("invite has been created", sl.User(user), slog.Any("room", room), slog.String("invite_id", invite.Id))
+
+		render.JSON(w, http.StatusCreated, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Invite: types.NewInvite(invite, token),
+			},
+		})
+	})
+}
@@ -0,0 +1,16 @@
+package invitelist
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data Data `json:"data"`
+}
+
+type Data struct {
+	Invites []*types.InviteView `json:"invites"`
+	Size    int                 `json:"size"`
+}
@@ -0,0 +1,72 @@
+package invitelist
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	Invites(roomUuid string) ([]*models.Invite, error)
+}
+
+// New lists the invites of a room to its owner.
+func New(log *slog.Logger, roomStorage RoomStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.invite.list.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+		if room.OwnerId != user.Id {
+			log.Info("unauthorized attempt to list invites", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		invites, err := roomStorage.Invites(room.Uuid)
+		if err != nil {
+			log.Error("failed to get the invites", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		invitesResponse := make([]*types.InviteView, 0, len(invites))
+		for _, invite := range invites {
+			invitesResponse = append(invitesResponse, types.NewInvite(invite, ""))
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Invites: invitesResponse,
+				Size:    len(invitesResponse),
+			},
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package inviterevoke
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	RevokeInvite(roomUuid, id string) error
+}
+
+// New lets the owner of a room revoke an invite, users who already joined with it stay members.
+func New(log *slog.Logger, roomStorage RoomStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.invite.revoke.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+		inviteId := mux.Vars(r)["invite_id"]
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+		if room.OwnerId != user.Id {
+			log.Info("unauthorized attempt to revoke an invite", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		err = roomStorage.RevokeInvite(room.Uuid, inviteId)
+		if errors.Is(err, storage.InviteNotFound) {
+			log.Info("invite doesn't exist", slog.String("invite_id", inviteId))
+			render.JSON(w, http.StatusNotFound, api.Err("invite is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke the invite", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("invite has been revoked", sl.User(user), slog.Any("room", room), slog.String("invite_id", inviteId))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
@@ -0,0 +1,5 @@
+package roomkick
+
+type Request struct {
+	UserId int64 `json:"user_id" validate:"required"`
+}
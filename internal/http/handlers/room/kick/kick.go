@@ -0,0 +1,91 @@
+package roomkick
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+}
+
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+}
+
+func New(log *slog.Logger, hub *roomchat.Hub, roomStorage RoomStorage, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.kick.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		target, err := userStorage.UserById(body.UserId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user doesn't exist", slog.Int64("user_id", body.UserId))
+			render.JSON(w, http.StatusNotFound, api.Err("user is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the user", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		err = hub.Kick(room, user, target)
+		if errors.Is(err, roomchat.NotAllowed) {
+			log.Info("unauthorized attempt to kick the user", sl.User(user), slog.Any("room", room), slog.Int64("target_id", target.Id))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to kick the user", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("user has been kicked", sl.User(user), slog.Any("room", room), slog.Int64("target_id", target.Id))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
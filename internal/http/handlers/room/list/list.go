@@ -3,7 +3,9 @@ package roomlist
 import (
 	"log/slog"
 	"net/http"
+	"slices"
 
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
 	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
 	"github.com/guluzadehh/go_chat/internal/lib/api"
 	"github.com/guluzadehh/go_chat/internal/lib/render"
@@ -20,7 +22,15 @@ type UserStorage interface {
 	UsersWithIds(ids []int64) (map[int64]*models.User, error)
 }
 
-func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage) http.Handler {
+type Presence interface {
+	OnlineCounts(roomUuids []string) (map[string]int, error)
+}
+
+type ReadStorage interface {
+	UnreadCounts(userId int64, roomUuids []string) (map[string]int, error)
+}
+
+func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage, presence Presence, readStorage ReadStorage) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.room.list.New"
 
@@ -33,9 +43,14 @@ func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage) htt
 			return
 		}
 
+		// direct conversations are listed by their users only
+		rooms = slices.DeleteFunc(rooms, func(room *models.Room) bool { return room.IsDirect() })
+
 		owner_ids := make([]int64, 0)
+		uuids := make([]string, 0, len(rooms))
 		for _, room := range rooms {
 			owner_ids = append(owner_ids, room.OwnerId)
+			uuids = append(uuids, room.Uuid)
 		}
 
 		owners, err := userStorage.UsersWithIds(owner_ids)
@@ -45,9 +60,25 @@ func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage) htt
 			return
 		}
 
+		online, err := presence.OnlineCounts(uuids)
+		if err != nil {
+			log.Error("failed to get the online counts of rooms", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		unread, err := readStorage.UnreadCounts(user.Id, uuids)
+		if err != nil {
+			log.Error("failed to get the unread counts of rooms", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
 		roomsResponse := make([]*types.RoomView, 0)
 		for _, room := range rooms {
-			roomsResponse = append(roomsResponse, types.NewRoom(room, owners[room.OwnerId]))
+			roomsResponse = append(roomsResponse, types.NewRoom(room, owners[room.OwnerId], online[room.Uuid], unread[room.Uuid]))
 		}
 
 		render.JSON(w, http.StatusOK, Response{
@@ -0,0 +1,16 @@
+package roommembers
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Members []*types.UserView `json:"members"`
+	Size    int               `json:"size"`
+}
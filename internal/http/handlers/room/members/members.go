@@ -0,0 +1,85 @@
+package roommembers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	roommessages "github.com/guluzadehh/go_chat/internal/http/handlers/room/messages"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+}
+
+// New lists the users online in the room, private rooms take the same password header as
+// the room history.
+func New(log *slog.Logger, hub *roomchat.Hub, roomStorage RoomStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.members.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		allowed, err := roomaccess.Allowed(roomStorage, room, user, r.Header.Get(roommessages.PasswordHeader))
+		if err != nil {
+			log.Error("failed to check room access", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if !allowed {
+			log.Info("unauthorized access to room members", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		roster, err := hub.Roster(room.Uuid)
+		if err != nil {
+			log.Error("failed to get the roster", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		members := make([]*types.UserView, 0, len(roster))
+		for _, member := range roster {
+			members = append(members, types.NewUser(member))
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Members: members,
+				Size:    len(members),
+			},
+		})
+	})
+}
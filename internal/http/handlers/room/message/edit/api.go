@@ -0,0 +1,5 @@
+package messageedit
+
+type Request struct {
+	Text string `json:"text" validate:"required,max=4096"`
+}
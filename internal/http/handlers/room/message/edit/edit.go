@@ -0,0 +1,108 @@
+package messageedit
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	roommessages "github.com/guluzadehh/go_chat/internal/http/handlers/room/messages"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+}
+
+func New(log *slog.Logger, hub *roomchat.Hub, roomStorage RoomStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.message.edit.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		messageId, err := strconv.ParseInt(mux.Vars(r)["message_id"], 10, 64)
+		if err != nil {
+			log.Info("invalid message id", slog.String("message_id", mux.Vars(r)["message_id"]))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid message id"))
+			return
+		}
+
+		var body Request
+		err = api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		allowed, err := roomaccess.Allowed(roomStorage, room, user, r.Header.Get(roommessages.PasswordHeader))
+		if err != nil {
+			log.Error("failed to check room access", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if !allowed {
+			log.Info("unauthorized attempt to edit the message", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		err = hub.EditMessage(room, user, messageId, body.Text)
+		switch {
+		case errors.Is(err, storage.MessageNotFound):
+			log.Info("message doesn't exist", slog.Any("room", room), slog.Int64("message_id", messageId))
+			render.JSON(w, http.StatusNotFound, api.Err("message is not found"))
+			return
+		case errors.Is(err, roomchat.NotAllowed), errors.Is(err, roomchat.Muted):
+			log.Info("unauthorized attempt to edit the message", sl.User(user), slog.Any("room", room), slog.Int64("message_id", messageId))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		case errors.Is(err, roomchat.EditWindowPassed):
+			log.Info("edit window has passed", sl.User(user), slog.Int64("message_id", messageId))
+			render.JSON(w, http.StatusConflict, api.Err("message can no longer be edited"))
+			return
+		case err != nil:
+			log.Error("failed to edit the message", slog.Any("room", room), slog.Int64("message_id", messageId), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("message has been edited", sl.User(user), slog.Any("room", room), slog.Int64("message_id", messageId))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
@@ -0,0 +1,18 @@
+package messagereplies
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+)
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	ThreadRoot int64               `json:"thread_root"`
+	Replies    []*roomchat.Message `json:"replies"`
+	Size       int                 `json:"size"`
+	NextBefore int64               `json:"next_before,omitempty"`
+}
@@ -0,0 +1,169 @@
+package messagereplies
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	roommessages "github.com/guluzadehh/go_chat/internal/http/handlers/room/messages"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+}
+
+type MessageStorage interface {
+	MessageById(id int64) (*models.Message, error)
+	ThreadReplies(rootId, before int64, limit int) ([]*models.Message, error)
+	Reactions(messageIds []int64) (map[int64][]*models.Reaction, error)
+}
+
+type UserStorage interface {
+	UsersWithIds(ids []int64) (map[int64]*models.User, error)
+}
+
+// New pages through the replies in the thread of the message, the replies of a reply are
+// the ones of its thread.
+func New(log *slog.Logger, roomStorage RoomStorage, messageStorage MessageStorage, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.message.replies.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		messageId, err := strconv.ParseInt(mux.Vars(r)["message_id"], 10, 64)
+		if err != nil {
+			log.Info("invalid message id", slog.String("message_id", mux.Vars(r)["message_id"]))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid message id"))
+			return
+		}
+
+		before, err := api.QueryInt(r, "before", 0)
+		if err != nil || before < 0 {
+			log.Info("invalid before parameter", slog.String("before", r.URL.Query().Get("before")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid before parameter"))
+			return
+		}
+
+		limit, err := api.QueryInt(r, "limit", defaultLimit)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			log.Info("invalid limit parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid limit parameter"))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		allowed, err := roomaccess.Allowed(roomStorage, room, user, r.Header.Get(roommessages.PasswordHeader))
+		if err != nil {
+			log.Error("failed to check room access", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if !allowed {
+			log.Info("unauthorized access to thread replies", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		msg, err := messageStorage.MessageById(messageId)
+		if err == nil && msg.RoomUuid != room.Uuid {
+			err = storage.MessageNotFound
+		}
+		if errors.Is(err, storage.MessageNotFound) {
+			log.Info("message doesn't exist", slog.Any("room", room), slog.Int64("message_id", messageId))
+			render.JSON(w, http.StatusNotFound, api.Err("message is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the message", slog.Int64("message_id", messageId), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		rootId := msg.Id
+		if msg.IsReply() {
+			rootId = msg.ThreadRoot
+		}
+
+		replies, err := messageStorage.ThreadReplies(rootId, before, int(limit))
+		if err != nil {
+			log.Error("failed to get thread replies", slog.Int64("thread_root", rootId), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		author_ids := make([]int64, 0)
+		ids := make([]int64, 0, len(replies))
+		for _, reply := range replies {
+			author_ids = append(author_ids, reply.UserId)
+			ids = append(ids, reply.Id)
+		}
+
+		authors, err := userStorage.UsersWithIds(author_ids)
+		if err != nil {
+			log.Error("failed to get the authors of replies", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		reactions, err := messageStorage.Reactions(ids)
+		if err != nil {
+			log.Error("failed to get the reactions of replies", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		repliesResponse := make([]*roomchat.Message, 0, len(replies))
+		for _, reply := range replies {
+			repliesResponse = append(repliesResponse, roomchat.NewStoredMessage(reply, authors[reply.UserId], reactions[reply.Id], nil))
+		}
+
+		var nextBefore int64
+		if len(replies) == int(limit) {
+			nextBefore = replies[0].Id
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				ThreadRoot: rootId,
+				Replies:    repliesResponse,
+				Size:       len(repliesResponse),
+				NextBefore: nextBefore,
+			},
+		})
+	})
+}
@@ -0,0 +1,18 @@
+package roommessages
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+)
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Messages   []*roomchat.Message `json:"messages"`
+	Size       int                 `json:"size"`
+	NextBefore int64               `json:"next_before,omitempty"`
+	NextAfter  int64               `json:"next_after,omitempty"`
+}
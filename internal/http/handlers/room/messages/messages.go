@@ -0,0 +1,167 @@
+package roommessages
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/lib/roomchat"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+)
+
+// PasswordHeader carries the password of a private room for users
+// who haven't been let in through the chat yet.
+const PasswordHeader = "X-Room-Password"
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+}
+
+type MessageStorage interface {
+	Messages(roomUuid string, before int64, limit int) ([]*models.Message, error)
+	MessagesAfter(roomUuid string, after int64, limit int) ([]*models.Message, error)
+	Reactions(messageIds []int64) (map[int64][]*models.Reaction, error)
+	Threads(rootIds []int64) (map[int64]*models.Thread, error)
+}
+
+type UserStorage interface {
+	UsersWithIds(ids []int64) (map[int64]*models.User, error)
+}
+
+func New(log *slog.Logger, roomStorage RoomStorage, messageStorage MessageStorage, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.messages.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		before, err := api.QueryInt(r, "before", 0)
+		if err != nil || before < 0 {
+			log.Info("invalid before parameter", slog.String("before", r.URL.Query().Get("before")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid before parameter"))
+			return
+		}
+
+		after, err := api.QueryInt(r, "after", 0)
+		if err != nil || after < 0 || (after > 0 && before > 0) {
+			log.Info("invalid after parameter", slog.String("after", r.URL.Query().Get("after")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid after parameter"))
+			return
+		}
+
+		limit, err := api.QueryInt(r, "limit", defaultLimit)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			log.Info("invalid limit parameter", slog.String("limit", r.URL.Query().Get("limit")))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid limit parameter"))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if err != nil {
+			if errors.Is(err, storage.RoomNotFound) {
+				log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+				render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+				return
+			}
+
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+
+		allowed, err := roomaccess.Allowed(roomStorage, room, user, r.Header.Get(PasswordHeader))
+		if err != nil {
+			log.Error("failed to check room access", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if !allowed {
+			log.Info("unauthorized access to room history", sl.User(user), slog.Any("room", room))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		var messages []*models.Message
+		if after > 0 {
+			messages, err = messageStorage.MessagesAfter(room.Uuid, after, int(limit))
+		} else {
+			messages, err = messageStorage.Messages(room.Uuid, before, int(limit))
+		}
+		if err != nil {
+			log.Error("failed to get room messages", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		author_ids := make([]int64, 0)
+		ids := make([]int64, 0, len(messages))
+		for _, msg := range messages {
+			author_ids = append(author_ids, msg.UserId)
+			ids = append(ids, msg.Id)
+		}
+
+		authors, err := userStorage.UsersWithIds(author_ids)
+		if err != nil {
+			log.Error("failed to get the authors of messages", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		reactions, err := messageStorage.Reactions(ids)
+		if err != nil {
+			log.Error("failed to get the reactions of messages", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		threads, err := messageStorage.Threads(ids)
+		if err != nil {
+			log.Error("failed to get the threads of messages", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		messagesResponse := make([]*roomchat.Message, 0, len(messages))
+		for _, msg := range messages {
+			messagesResponse = append(messagesResponse, roomchat.NewStoredMessage(msg, authors[msg.UserId], reactions[msg.Id], threads[msg.Id]))
+		}
+
+		var nextBefore, nextAfter int64
+		if len(messages) == int(limit) {
+			if after > 0 {
+				nextAfter = messages[len(messages)-1].Id
+			} else {
+				nextBefore = messages[0].Id
+			}
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Messages:   messagesResponse,
+				Size:       len(messagesResponse),
+				NextBefore: nextBefore,
+				NextAfter:  nextAfter,
+			},
+		})
+	})
+}
@@ -0,0 +1,7 @@
+package roommute
+
+// Request mutes the user for Duration seconds.
+type Request struct {
+	UserId   int64 `json:"user_id" validate:"required"`
+	Duration int64 `json:"duration" validate:"required,min=1"`
+}
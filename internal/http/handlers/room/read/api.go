@@ -0,0 +1,5 @@
+package roomread
+
+type Request struct {
+	MessageId int64 `json:"message_id" validate:"required,min=1"`
+}
@@ -0,0 +1,7 @@
+package roomrole
+
+import "github.com/guluzadehh/go_chat/internal/models"
+
+type Request struct {
+	Role models.Role `json:"role" validate:"required,oneof=moderator member"`
+}
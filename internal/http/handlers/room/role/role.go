@@ -0,0 +1,99 @@
+package roomrole
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+type RoomStorage interface {
+	RoomByUuid(uuid string) (*models.Room, error)
+	SetRoomRole(uuid string, userId int64, role models.Role) error
+}
+
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+}
+
+// New lets the owner of a room promote users to moderators and demote them back.
+func New(log *slog.Logger, roomStorage RoomStorage, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.room.role.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		roomUuid := mux.Vars(r)["room_uuid"]
+
+		targetId, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+		if err != nil {
+			log.Info("invalid user id", slog.String("user_id", mux.Vars(r)["user_id"]))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid user id"))
+			return
+		}
+
+		var body Request
+		err = api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		room, err := roomStorage.RoomByUuid(roomUuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			log.Info("room doesn't exist", slog.String("uuid", roomUuid))
+			render.JSON(w, http.StatusNotFound, api.Err("room is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the room", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		user := authmdw.User(r)
+		if room.OwnerId != user.Id || targetId == room.OwnerId {
+			log.Info("unauthorized attempt to change a role", sl.User(user), slog.Any("room", room), slog.Int64("target_id", targetId))
+			render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+			return
+		}
+
+		target, err := userStorage.UserById(targetId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user doesn't exist", slog.Int64("user_id", targetId))
+			render.JSON(w, http.StatusNotFound, api.Err("user is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the user", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := roomStorage.SetRoomRole(room.Uuid, target.Id, body.Role); err != nil {
+			log.Error("failed to set the role", slog.Any("room", room), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("role has been set", slog.Any("room", room), slog.Int64("target_id", target.Id), slog.String("role", string(body.Role)))
+
+		render.JSON(w, http.StatusOK, api.Ok())
+	})
+}
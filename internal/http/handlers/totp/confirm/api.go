@@ -0,0 +1,25 @@
+package totpconfirm
+
+import (
+	"log/slog"
+
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+)
+
+type Request struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// LogValue keeps the code out of the logs.
+func (r Request) LogValue() slog.Value {
+	return slog.GroupValue(slog.Bool("has_code", r.Code != ""))
+}
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
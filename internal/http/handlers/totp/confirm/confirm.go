@@ -0,0 +1,115 @@
+package totpconfirm
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/loginlimit"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/lib/totp"
+)
+
+type UserStorage interface {
+	EnableTotp(userId int64) error
+	ReplaceRecoveryCodes(userId int64, hashes []string) error
+}
+
+// New enables two-factor authentication once the user proves the secret has been set up
+// with a code of it, the recovery codes are only shown here. Invalid codes count towards
+// the login lockout of the user.
+func New(log *slog.Logger, config *config.Config, limiter *loginlimit.Limiter, userStorage UserStorage, stepStorage totp.StepStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.totp.confirm.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		var body Request
+		err := api.DecodeBody(log, w, r, &body)
+		if err != nil {
+			return
+		}
+
+		v := validator.New()
+		if err := v.Struct(body); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, http.StatusBadRequest, api.ValidationError(validateErr))
+			return
+		}
+
+		user := authmdw.User(r)
+		if user.TotpEnabled {
+			log.Info("two-factor authentication is already enabled", sl.User(user))
+			render.JSON(w, http.StatusConflict, api.Err("two-factor authentication is already enabled"))
+			return
+		}
+		if user.TotpSecret == "" {
+			log.Info("user hasn't enrolled in two-factor authentication", sl.User(user))
+			render.JSON(w, http.StatusBadRequest, api.Err("two-factor authentication isn't set up"))
+			return
+		}
+
+		ip := api.ClientIP(r)
+
+		ok, lockout, err := limiter.Attempt(user.Username, ip, func() (bool, error) {
+			return totp.Verify(stepStorage, user, body.Code)
+		})
+		if err != nil {
+			log.Error("failed to verify the code", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		if lockout > 0 {
+			log.Warn("code attempts are locked out", sl.User(user), slog.String("ip", ip), slog.Duration("lockout", lockout))
+			loginlimit.TooManyAttempts(w, lockout)
+			return
+		}
+		if !ok {
+			log.Info("invalid code", sl.User(user), slog.String("ip", ip))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid code"))
+			return
+		}
+
+		if err := limiter.Succeed(user.Username); err != nil {
+			log.Error("failed to reset the failed login attempts", sl.User(user), sl.Err(err))
+		}
+
+		codes, err := totp.RecoveryCodes(config.TOTP.RecoveryCodes)
+		if err != nil {
+			log.Error("can't generate recovery codes", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		hashes := make([]string, 0, len(codes))
+		for _, code := range codes {
+			hashes = append(hashes, totp.HashRecoveryCode(code))
+		}
+
+		if err := userStorage.ReplaceRecoveryCodes(user.Id, hashes); err != nil {
+			log.Error("failed to save the recovery codes", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := userStorage.EnableTotp(user.Id); err != nil {
+			log.Error("failed to enable two-factor authentication", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("two-factor authentication has been enabled", sl.User(user))
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				RecoveryCodes: codes,
+			},
+		})
+	})
+}
@@ -0,0 +1,13 @@
+package totpenroll
+
+import "github.com/guluzadehh/go_chat/internal/lib/api"
+
+type Response struct {
+	api.Response
+	Data `json:"data"`
+}
+
+type Data struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
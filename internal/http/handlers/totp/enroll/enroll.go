@@ -0,0 +1,57 @@
+package totpenroll
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/lib/totp"
+)
+
+type UserStorage interface {
+	SetTotpSecret(userId int64, secret string) error
+}
+
+// New generates a new secret for the user, two-factor authentication is enabled once
+// a code of it is confirmed.
+func New(log *slog.Logger, config *config.Config, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.totp.enroll.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		user := authmdw.User(r)
+		if user.TotpEnabled {
+			log.Info("two-factor authentication is already enabled", sl.User(user))
+			render.JSON(w, http.StatusConflict, api.Err("two-factor authentication is already enabled"))
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			log.Error("can't generate totp secret", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		if err := userStorage.SetTotpSecret(user.Id, secret); err != nil {
+			log.Error("failed to save the totp secret", sl.User(user), sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+		log.Info("user has enrolled in two-factor authentication", sl.User(user))
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data: Data{
+				Secret: secret,
+				URI:    totp.URI(secret, config.TOTP.Issuer, user.Username),
+			},
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package userget
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+type UserStorage interface {
+	UserById(id int64) (*models.User, error)
+}
+
+func New(log *slog.Logger, userStorage UserStorage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.user.get.New"
+
+		log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+		userId, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+		if err != nil {
+			log.Info("invalid user id", slog.String("user_id", mux.Vars(r)["user_id"]))
+			render.JSON(w, http.StatusBadRequest, api.Err("invalid user id"))
+			return
+		}
+
+		user, err := userStorage.UserById(userId)
+		if errors.Is(err, storage.UserNotFound) {
+			log.Info("user doesn't exist", slog.Int64("user_id", userId))
+			render.JSON(w, http.StatusNotFound, api.Err("user is not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get the user", sl.Err(err))
+			render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
+			return
+		}
+
+		render.JSON(w, http.StatusOK, Response{
+			Response: api.Ok(),
+			Data:     Data{User: types.NewUser(user)},
+		})
+	})
+}
@@ -0,0 +1,33 @@
+package adminmdw
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/authmdw"
+	"github.com/guluzadehh/go_chat/internal/http/middlewares/requestmdw"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+)
+
+// RequireAdmin lets only admins through, it must be used after authmdw.Authorize.
+func RequireAdmin(log *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middlewares.adminMdw.RequireAdmin"
+
+			log := sl.ForHandler(log, op, requestmdw.GetReqId(r))
+
+			user := authmdw.User(r)
+			if user == nil || !user.IsAdmin {
+				log.Info("non-admin access attempt", slog.String("path", r.URL.Path))
+				render.JSON(w, http.StatusForbidden, api.Err("you are not allowed"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package authmdw
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/guluzadehh/go_chat/internal/lib/render"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
 )
 
 type contextKey string
@@ -21,10 +23,14 @@ type contextKey string
 const userContextKey contextKey = "user"
 
 type AuthStorage interface {
-	UserByUsername(username string) (*models.User, error)
+	UserById(id int64) (*models.User, error)
 }
 
-func Authorize(log *slog.Logger, config *config.Config, authStorage AuthStorage) mux.MiddlewareFunc {
+type TokenStorage interface {
+	TokenVersion(userId int64) (int64, error)
+}
+
+func Authorize(log *slog.Logger, config *config.Config, authStorage AuthStorage, tokenStorage TokenStorage) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			const op = "middlewares.authMdw.Authorize"
@@ -54,27 +60,38 @@ func Authorize(log *slog.Logger, config *config.Config, authStorage AuthStorage)
 			}
 
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			token, err := jwt.Verify(tokenStr, config)
+			token, err := jwt.Verify(tokenStr, jwt.TypeAccess, config)
 			if err != nil {
-				log.Info("access token is invalid", slog.String("invalid_access_token", tokenStr), sl.Err(err))
+				log.Info("access token is invalid", sl.Err(err))
 				render.JSON(w, http.StatusUnauthorized, authFailResponse())
 				return
 			}
 
-			username, err := token.Claims.GetSubject()
+			user, err := authStorage.UserById(token.UserId)
+			if errors.Is(err, storage.UserNotFound) {
+				log.Info("user of the access token doesn't exist", slog.Int64("user_id", token.UserId))
+				render.JSON(w, http.StatusUnauthorized, authFailResponse())
+				return
+			}
 			if err != nil {
-				log.Error("error while getting the subject from access token", sl.Err(err))
+				log.Error("failed to get user by id from storage", sl.Err(err))
 				render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 				return
 			}
 
-			user, err := authStorage.UserByUsername(username)
+			version, err := tokenStorage.TokenVersion(user.Id)
 			if err != nil {
-				log.Error("failed to get user by username from storage", sl.Err(err))
+				log.Error("failed to get the token version", sl.Err(err))
 				render.JSON(w, http.StatusInternalServerError, api.UnexpectedError())
 				return
 			}
 
+			if token.Version != version {
+				log.Info("access token has been revoked", sl.User(user))
+				render.JSON(w, http.StatusUnauthorized, authFailResponse())
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), userContextKey, user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
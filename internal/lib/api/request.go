@@ -2,7 +2,11 @@ package api
 
 import (
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/guluzadehh/go_chat/internal/lib/render"
 	"github.com/guluzadehh/go_chat/internal/lib/sl"
@@ -20,3 +24,28 @@ func DecodeBody(log *slog.Logger, w http.ResponseWriter, r *http.Request, v inte
 
 	return nil
 }
+
+// QueryInt parses an integer query parameter, falling back to def when it is absent.
+func QueryInt(r *http.Request, key string, def int64) (int64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ClientIP returns the ip the request has been sent from.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// SetRetryAfter tells the client how long to wait before retrying the request.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -54,6 +54,22 @@ func alias(name string) string {
 		return "password"
 	case "ConfPassword":
 		return "confirm password"
+	case "UserId":
+		return "user id"
+	case "RecoveryCode":
+		return "recovery code"
+	case "CurrentPassword":
+		return "current password"
+	case "Text":
+		return "text"
+	case "MessageId":
+		return "message id"
+	case "MessageIds":
+		return "message ids"
+	case "DisplayName":
+		return "display name"
+	case "AvatarUrl":
+		return "avatar url"
 	default:
 		return name
 	}
@@ -80,6 +96,16 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			msg = fmt.Sprintf("field %s must contain on of the following characters: %s.", field, alias(err.Param()))
 		case "eqfield":
 			msg = fmt.Sprintf("field %s is not equal to %s field.", field, alias(err.Param()))
+		case "required_without":
+			msg = fmt.Sprintf("field %s is required without %s.", field, alias(err.Param()))
+		case "email":
+			msg = fmt.Sprintf("field %s must be a valid email address.", field)
+		case "http_url":
+			msg = fmt.Sprintf("field %s must be a valid http url.", field)
+		case "len":
+			msg = fmt.Sprintf("field %s length must be %s.", field, err.Param())
+		case "oneof":
+			msg = fmt.Sprintf("field %s must be one of: %s.", field, err.Param())
 		case "passwordpattern":
 			msg = "field password must contain at least one letter, one number, and one special character."
 		default:
@@ -4,13 +4,25 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
+	"net/http"
+
+	"github.com/guluzadehh/go_chat/internal/config"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// MaxPasswordLen is the number of bytes of a password bcrypt can hash.
+const MaxPasswordLen = 72
+
+// DummyPasswordHash is checked against when there is no user to check the password of,
+// so that an unknown username takes as long to reject as a wrong password.
+const DummyPasswordHash = "$2a$14$ZwmV.ibakbaRIyAGW/LZmO.bymu1vsftbV6ZNt9JzL39rkKCH/Mpq"
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -21,6 +33,28 @@ func CheckPasswordHash(hash, password string) bool {
 	return err == nil
 }
 
+// IsPasswordHash reports whether the value is a bcrypt hash rather than a plaintext password.
+func IsPasswordHash(value string) bool {
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
+}
+
+// GenerateToken returns a random url-safe token, such as a password reset token.
+func GenerateToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashToken hashes a random token for storage, so that a leaked storage can't be used to redeem it.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func Encrypt(text string, secretKey []byte) (string, error) {
 	block, err := aes.NewCipher(secretKey)
 	if err != nil {
@@ -70,3 +104,46 @@ func Decrypt(encryptedText string, secretKey []byte) (string, error) {
 
 	return string(plaintext), nil
 }
+
+// legacyRefreshCookiePath is the path the refresh cookie used to be set on, a cookie left
+// there would be sent to the refresh endpoint ahead of the current one.
+const legacyRefreshCookiePath = "/api/refresh"
+
+// SetRefreshCookie stores the encrypted refresh token on the client. The cookie is sent
+// to the whole api so that logout can revoke the token too, the one set on the legacy
+// path is expired.
+func SetRefreshCookie(w http.ResponseWriter, value string, config *config.Config) {
+	expireLegacyRefreshCookie(w, config)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.JWT.Refresh.CookieName,
+		Value:    value,
+		SameSite: http.SameSiteNoneMode,
+		Path:     "/api",
+		HttpOnly: true,
+		MaxAge:   int(config.JWT.Refresh.Expire.Seconds()),
+	})
+}
+
+func ClearRefreshCookie(w http.ResponseWriter, config *config.Config) {
+	expireLegacyRefreshCookie(w, config)
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.JWT.Refresh.CookieName,
+		Value:    "",
+		SameSite: http.SameSiteNoneMode,
+		Path:     "/api",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+}
+
+func expireLegacyRefreshCookie(w http.ResponseWriter, config *config.Config) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.JWT.Refresh.CookieName,
+		Value:    "",
+		SameSite: http.SameSiteNoneMode,
+		Path:     legacyRefreshCookiePath,
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+}
@@ -2,53 +2,139 @@ package jwt
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/models"
 )
 
-func AccessToken(username string, config *config.Config) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": username,
-		"exp": time.Now().Add(config.JWT.Access.Expire).Unix(),
-		"iat": time.Now().Unix(),
+// Token types, a token is only accepted where its type is expected.
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+	TypeInvite  = "invite"
+	// TypeChallenge is exchanged for the access token once the second factor is checked.
+	TypeChallenge = "challenge"
+)
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Type string `json:"typ"`
+
+	// UserId and Version identify the user of access and refresh tokens, Version is
+	// the token version of the user the token has been issued with.
+	UserId  int64 `json:"uid,omitempty"`
+	Version int64 `json:"ver,omitempty"`
+
+	// Family is the refresh token family, started on login and carried over by every rotation.
+	Family string `json:"fam,omitempty"`
+
+	// Room is the room of invite tokens.
+	Room string `json:"room,omitempty"`
+}
+
+// AccessToken issues the access token of the user, version is the token version of the user.
+func AccessToken(user *models.User, version int64, config *config.Config) (string, error) {
+	now := time.Now()
+
+	return keys.sign(&Claims{
+		RegisteredClaims: registeredClaims(user, uuid.NewString(), now, now.Add(config.JWT.Access.Expire), config),
+		Type:             TypeAccess,
+		UserId:           user.Id,
+		Version:          version,
 	})
+}
 
-	tokenStr, err := token.SignedString([]byte(config.JWT.SecretKey))
-	if err != nil {
-		return "", err
-	}
+// RefreshToken issues the refresh token jti of the family.
+func RefreshToken(user *models.User, family, jti string, version int64, config *config.Config) (string, error) {
+	now := time.Now()
+
+	return keys.sign(&Claims{
+		RegisteredClaims: registeredClaims(user, jti, now, now.Add(config.JWT.Refresh.Expire), config),
+		Type:             TypeRefresh,
+		UserId:           user.Id,
+		Version:          version,
+		Family:           family,
+	})
+}
 
-	return tokenStr, nil
+func ChallengeToken(user *models.User, config *config.Config) (string, error) {
+	now := time.Now()
+
+	return keys.sign(&Claims{
+		RegisteredClaims: registeredClaims(user, uuid.NewString(), now, now.Add(config.JWT.Challenge.Expire), config),
+		Type:             TypeChallenge,
+		UserId:           user.Id,
+	})
 }
 
-func RefreshToken(username string, config *config.Config) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": username,
-		"exp": time.Now().Add(config.JWT.Refresh.Expire).Unix(),
-		"iat": time.Now().Unix(),
+func InviteToken(roomUuid, inviteId string, expiresAt time.Time, config *config.Config) (string, error) {
+	return keys.sign(&Claims{
+		RegisteredClaims: registeredClaims(nil, inviteId, time.Now(), expiresAt, config),
+		Type:             TypeInvite,
+		Room:             roomUuid,
 	})
+}
 
-	tokenStr, err := token.SignedString([]byte(config.JWT.SecretKey))
+// VerifyInvite checks the invite token and returns the room and the invite it was issued for.
+func VerifyInvite(tokenStr string, config *config.Config) (roomUuid, inviteId string, err error) {
+	claims, err := Verify(tokenStr, TypeInvite, config)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return tokenStr, nil
+	if claims.Room == "" || claims.ID == "" {
+		return "", "", fmt.Errorf("invalid invite token")
+	}
+
+	return claims.Room, claims.ID, nil
 }
 
-func Verify(tokenStr string, config *config.Config) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(config.JWT.SecretKey), nil
-	})
+// Verify checks the signature, the lifetime, the issuer and the audience of the token
+// and that it is of the expected type.
+func Verify(tokenStr string, typ string, config *config.Config) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenStr,
+		&Claims{},
+		keys.verificationKey,
+		jwt.WithValidMethods(keys.methods()),
+		jwt.WithIssuer(config.JWT.Issuer),
+		jwt.WithAudience(config.JWT.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
 
-	return token, nil
+	if claims.Type != typ {
+		return nil, fmt.Errorf("unexpected token type %q", claims.Type)
+	}
+
+	return claims, nil
+}
+
+func registeredClaims(user *models.User, jti string, issuedAt, expiresAt time.Time, config *config.Config) jwt.RegisteredClaims {
+	claims := jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    config.JWT.Issuer,
+		Audience:  jwt.ClaimStrings{config.JWT.Audience},
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		NotBefore: jwt.NewNumericDate(issuedAt),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	if user != nil {
+		claims.Subject = strconv.FormatInt(user.Id, 10)
+	}
+
+	return claims
 }
@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Issuer = "gochat"
+	cfg.JWT.Audience = "gochat"
+	cfg.JWT.Access.Expire = time.Hour
+	cfg.JWT.Refresh.Expire = 24 * time.Hour
+	cfg.JWT.Challenge.Expire = 5 * time.Minute
+	return cfg
+}
+
+// useTestKeys replaces the key set with a fresh Ed25519 signing key for the test.
+func useTestKeys(t *testing.T) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := &Key{Id: "test", Method: jwt.SigningMethodEdDSA, Private: private, Public: public}
+
+	prev := keys
+	keys = &KeySet{signing: key, keys: map[string]*Key{key.Id: key}}
+	t.Cleanup(func() { keys = prev })
+}
+
+func TestVerifyType(t *testing.T) {
+	useTestKeys(t)
+	cfg := testConfig()
+	user := &models.User{Id: 7, Username: "alice"}
+
+	issue := map[string]func() (string, error){
+		TypeAccess:    func() (string, error) { return AccessToken(user, 1, cfg) },
+		TypeRefresh:   func() (string, error) { return RefreshToken(user, "family", "jti", 1, cfg) },
+		TypeChallenge: func() (string, error) { return ChallengeToken(user, cfg) },
+		TypeInvite:    func() (string, error) { return InviteToken("room", "invite", time.Now().Add(time.Hour), cfg) },
+	}
+
+	for issued, sign := range issue {
+		token, err := sign()
+		if err != nil {
+			t.Fatalf("issue %s token: %v", issued, err)
+		}
+
+		for expected := range issue {
+			_, err := Verify(token, expected, cfg)
+			if ok := err == nil; ok != (issued == expected) {
+				t.Errorf("Verify of a %s token as %s: err = %v", issued, expected, err)
+			}
+		}
+	}
+}
+
+func TestVerifyClaims(t *testing.T) {
+	useTestKeys(t)
+	cfg := testConfig()
+	now := time.Now()
+
+	valid := func() *Claims {
+		return &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "jti",
+				Subject:   "7",
+				Issuer:    cfg.JWT.Issuer,
+				Audience:  jwt.ClaimStrings{cfg.JWT.Audience},
+				IssuedAt:  jwt.NewNumericDate(now),
+				NotBefore: jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			Type:   TypeAccess,
+			UserId: 7,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Claims)
+		ok     bool
+	}{
+		{"valid", func(c *Claims) {}, true},
+		{"other audiences too", func(c *Claims) { c.Audience = jwt.ClaimStrings{"other", cfg.JWT.Audience} }, true},
+		{"wrong issuer", func(c *Claims) { c.Issuer = "other" }, false},
+		{"missing issuer", func(c *Claims) { c.Issuer = "" }, false},
+		{"wrong audience", func(c *Claims) { c.Audience = jwt.ClaimStrings{"other"} }, false},
+		{"missing audience", func(c *Claims) { c.Audience = nil }, false},
+		{"missing type", func(c *Claims) { c.Type = "" }, false},
+		{"other type", func(c *Claims) { c.Type = TypeRefresh }, false},
+		{"expired", func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) }, false},
+		{"missing expiry", func(c *Claims) { c.ExpiresAt = nil }, false},
+		{"not yet valid", func(c *Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) }, false},
+		{"issued in the future", func(c *Claims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour)) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			token, err := keys.sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = Verify(token, TypeAccess, cfg)
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("Verify ok = %v, want %v, err = %v", ok, tt.ok, err)
+			}
+		})
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+	useTestKeys(t)
+	cfg := testConfig()
+	user := &models.User{Id: 7}
+
+	token, err := AccessToken(user, 0, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed with a key rotated out of the key set is rejected.
+	useTestKeys(t)
+	if _, err := Verify(token, TypeAccess, cfg); err == nil {
+		t.Error("Verify accepted a token signed with another key")
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{Type: TypeAccess}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(unsigned, TypeAccess, cfg); err == nil {
+		t.Error("Verify accepted an unsigned token")
+	}
+}
+
+func TestVerifyInvite(t *testing.T) {
+	useTestKeys(t)
+	cfg := testConfig()
+
+	token, err := InviteToken("room", "invite", time.Now().Add(time.Hour), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	room, invite, err := VerifyInvite(token, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room != "room" || invite != "invite" {
+		t.Errorf("VerifyInvite = %q, %q, want room, invite", room, invite)
+	}
+
+	expired, err := InviteToken("room", "invite", time.Now().Add(-time.Minute), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := VerifyInvite(expired, cfg); err == nil {
+		t.Error("VerifyInvite accepted an expired invite")
+	}
+}
@@ -0,0 +1,148 @@
+package loginlimit
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/lib/api"
+	"github.com/guluzadehh/go_chat/internal/lib/render"
+)
+
+// Kinds of the keys login attempts are limited by.
+const (
+	KindUser = "user"
+	KindIp   = "ip"
+
+	KindResetUser = "reset_user"
+	KindResetIp   = "reset_ip"
+)
+
+type Storage interface {
+	LoginLockout(key string) (time.Duration, error)
+	RecordLoginFailure(key string, ttl time.Duration) (int64, error)
+	LockLogin(key string, lockout, ttl time.Duration) error
+	ResetLoginFailures(key string) error
+}
+
+// Limiter locks out the usernames and the client ips with too many failed login attempts.
+type Limiter struct {
+	storage  Storage
+	config   config.LoginCfg
+	userKind string
+	ipKind   string
+}
+
+func New(storage Storage, config *config.Config) *Limiter {
+	return &Limiter{
+		storage:  storage,
+		config:   config.Login,
+		userKind: KindUser,
+		ipKind:   KindIp,
+	}
+}
+
+// NewReset limits the password reset requests apart from the logins, with the same limits.
+func NewReset(storage Storage, config *config.Config) *Limiter {
+	return &Limiter{
+		storage:  storage,
+		config:   config.Login,
+		userKind: KindResetUser,
+		ipKind:   KindResetIp,
+	}
+}
+
+// Check returns how long the login attempts for the username from the ip are locked for,
+// zero if they aren't.
+func (l *Limiter) Check(username, ip string) (time.Duration, error) {
+	userLockout, err := l.storage.LoginLockout(key(l.userKind, username))
+	if err != nil {
+		return 0, err
+	}
+
+	ipLockout, err := l.storage.LoginLockout(key(l.ipKind, ip))
+	if err != nil {
+		return 0, err
+	}
+
+	return max(userLockout, ipLockout), nil
+}
+
+// Fail records a failed login attempt and returns how long the following attempts are locked for.
+func (l *Limiter) Fail(username, ip string) (time.Duration, error) {
+	userLockout, err := l.fail(key(l.userKind, username), l.config.MaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	ipLockout, err := l.fail(key(l.ipKind, ip), l.config.MaxIpAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(userLockout, ipLockout), nil
+}
+
+// Attempt runs verify unless the username or the ip is locked out, a failed verification is
+// recorded. lockout is positive when the attempt has been refused or its failure locks out
+// the following ones, TooManyAttempts responds then. The failures are kept on success, the
+// caller forgets them with Succeed once the user is fully authenticated.
+func (l *Limiter) Attempt(username, ip string, verify func() (bool, error)) (ok bool, lockout time.Duration, err error) {
+	lockout, err = l.Check(username, ip)
+	if err != nil || lockout > 0 {
+		return false, lockout, err
+	}
+
+	ok, err = verify()
+	if err != nil || ok {
+		return ok, 0, err
+	}
+
+	lockout, err = l.Fail(username, ip)
+	return false, lockout, err
+}
+
+// TooManyAttempts responds to an attempt that has been locked out.
+func TooManyAttempts(w http.ResponseWriter, lockout time.Duration) {
+	api.SetRetryAfter(w, lockout)
+	render.JSON(w, http.StatusTooManyRequests, api.Err("too many attempts, try again later"))
+}
+
+// Succeed forgets the failed attempts of the username, the ones of the ip are kept
+// since a single ip may try many usernames.
+func (l *Limiter) Succeed(username string) error {
+	return l.storage.ResetLoginFailures(key(l.userKind, username))
+}
+
+func (l *Limiter) fail(key string, maxAttempts int) (time.Duration, error) {
+	failures, err := l.storage.RecordLoginFailure(key, l.config.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < int64(maxAttempts) {
+		return 0, nil
+	}
+
+	lockout := l.lockout(failures - int64(maxAttempts))
+	if err := l.storage.LockLogin(key, lockout, lockout+l.config.Window); err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// lockout doubles the base lockout for every failure past the limit.
+func (l *Limiter) lockout(excess int64) time.Duration {
+	lockout := l.config.Lockout
+	for i := int64(0); i < excess && lockout < l.config.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, l.config.MaxLockout)
+}
+
+func key(kind, subject string) string {
+	return fmt.Sprintf("%s:%s", kind, subject)
+}
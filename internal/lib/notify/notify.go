@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// Notifier kinds, picked by config.Notifier.Kind.
+const (
+	KindLog  = "log"
+	KindFile = "file"
+	KindSMTP = "smtp"
+)
+
+var NoAddress = errors.New("user has no email address")
+
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users out of band, such as password reset tokens.
+type Notifier interface {
+	Notify(user *models.User, msg Message) error
+}
+
+func New(log *slog.Logger, config *config.Config) (Notifier, error) {
+	switch config.Notifier.Kind {
+	case KindLog:
+		return &LogNotifier{log: log.With(slog.String("component", "notify/log"))}, nil
+	case KindFile:
+		return &FileNotifier{path: config.Notifier.FilePath}, nil
+	case KindSMTP:
+		return NewSMTPNotifier(config.Notifier.SMTP), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", config.Notifier.Kind)
+	}
+}
+
+// LogNotifier writes the messages to the log, it is meant for local development only.
+type LogNotifier struct {
+	log *slog.Logger
+}
+
+func (n *LogNotifier) Notify(user *models.User, msg Message) error {
+	n.log.Info("notification", slog.String("username", user.Username), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+	return nil
+}
+
+// FileNotifier appends the messages to a file.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Notify(user *models.User, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s to=%s subject=%q\n%s\n\n", time.Now().Format(time.RFC3339), user.Username, msg.Subject, msg.Body)
+	return err
+}
+
+// SMTPNotifier emails the messages, users without an email address can't be notified.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPNotifier(config config.SMTPCfg) *SMTPNotifier {
+	n := &SMTPNotifier{
+		addr: config.Address,
+		from: config.From,
+	}
+
+	if config.Username != "" {
+		host, _, _ := net.SplitHostPort(config.Address)
+		n.auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+
+	return n
+}
+
+func (n *SMTPNotifier) Notify(user *models.User, msg Message) error {
+	if user.Email == "" {
+		return NoAddress
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", user.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{user.Email}, []byte(b.String()))
+}
@@ -0,0 +1,89 @@
+package roomaccess
+
+import (
+	"crypto/subtle"
+
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+type MemberStorage interface {
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+}
+
+// Granted reports whether the user can enter the room without presenting its password:
+// the user isn't banned and the room is public, the user owns it or has already been let
+// in before. Direct conversations are only open to their two users.
+func Granted(memberStorage MemberStorage, room *models.Room, user *models.User) (bool, error) {
+	if room.IsDirect() {
+		return room.IsParticipant(user.Id), nil
+	}
+
+	if room.OwnerId == user.Id {
+		return true, nil
+	}
+
+	banned, err := memberStorage.IsBanned(room.Uuid, user.Id)
+	if err != nil || banned {
+		return false, err
+	}
+
+	if !room.IsPrivate() {
+		return true, nil
+	}
+
+	return memberStorage.IsRoomMember(room.Uuid, user.Id)
+}
+
+// Allowed reports whether the user can access the room, either granted or by presenting
+// the password of a private room. Banned users are never allowed.
+func Allowed(memberStorage MemberStorage, room *models.Room, user *models.User, password string) (bool, error) {
+	granted, err := Granted(memberStorage, room, user)
+	if err != nil || granted {
+		return granted, err
+	}
+
+	if room.IsDirect() || !room.IsPrivate() {
+		return false, nil
+	}
+
+	banned, err := memberStorage.IsBanned(room.Uuid, user.Id)
+	if err != nil || banned {
+		return false, err
+	}
+
+	return CheckPassword(room, password), nil
+}
+
+// CheckPassword compares the password with the room's one. Rooms created before passwords
+// were hashed still hold them in plaintext, those are compared in constant time.
+func CheckPassword(room *models.Room, password string) bool {
+	if NeedsRehash(room) {
+		return subtle.ConstantTimeCompare([]byte(room.Password), []byte(password)) == 1
+	}
+
+	return auth.CheckPasswordHash(room.Password, password)
+}
+
+// NeedsRehash reports whether the room password is still stored in plaintext.
+func NeedsRehash(room *models.Room) bool {
+	return room.IsPrivate() && !auth.IsPasswordHash(room.Password)
+}
+
+type RoleStorage interface {
+	RoomRole(uuid string, userId int64) (models.Role, error)
+}
+
+// Role returns the role of the user in the room, nobody moderates a direct conversation.
+func Role(roleStorage RoleStorage, room *models.Room, userId int64) (models.Role, error) {
+	if room.IsDirect() {
+		return models.RoleMember, nil
+	}
+
+	if room.OwnerId == userId {
+		return models.RoleOwner, nil
+	}
+
+	return roleStorage.RoomRole(room.Uuid, userId)
+}
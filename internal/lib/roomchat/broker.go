@@ -0,0 +1,133 @@
+package roomchat
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Broker fans room events out to every instance running a hub.
+type Broker interface {
+	Publish(channel string, payload []byte) error
+	// Subscribe calls the handler with every payload published to a channel matching the
+	// glob pattern, the hub subscribes once per instance rather than once per room.
+	Subscribe(pattern string, handler func(channel string, payload []byte)) (unsubscribe func(), err error)
+}
+
+// LocalBroker delivers events within the process, it is enough when a single instance is running.
+type LocalBroker struct {
+	subs map[string]map[int]func(string, []byte)
+	next int
+	mu   sync.RWMutex
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{
+		subs: make(map[string]map[int]func(string, []byte)),
+	}
+}
+
+func (b *LocalBroker) Publish(channel string, payload []byte) error {
+	b.mu.RLock()
+	var handlers []func(string, []byte)
+	for pattern, subs := range b.subs {
+		if ok, _ := path.Match(pattern, channel); !ok {
+			continue
+		}
+		for _, handler := range subs {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(channel, payload)
+	}
+
+	return nil
+}
+
+func (b *LocalBroker) Subscribe(pattern string, handler func(string, []byte)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[pattern]; !ok {
+		b.subs[pattern] = make(map[int]func(string, []byte))
+	}
+
+	id := b.next
+	b.next++
+	b.subs[pattern][id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subs[pattern], id)
+		if len(b.subs[pattern]) == 0 {
+			delete(b.subs, pattern)
+		}
+	}, nil
+}
+
+const roomChannelPrefix = "chat:room:"
+
+func roomChannel(roomUuid string) string {
+	return fmt.Sprintf("%s%s", roomChannelPrefix, roomUuid)
+}
+
+// roomOfChannel is the uuid of the room the channel carries the events of.
+func roomOfChannel(channel string) (string, bool) {
+	return strings.CutPrefix(channel, roomChannelPrefix)
+}
+
+// usersChannel carries the events directed at users rather than rooms.
+const usersChannel = "chat:users"
+
+// userEvent is delivered to the members of the user in every room but Skip, Revoke closes them.
+type userEvent struct {
+	UserId int64    `json:"user_id"`
+	Msg    *Message `json:"msg,omitempty"`
+	Revoke bool     `json:"revoke,omitempty"`
+	Skip   string   `json:"skip,omitempty"`
+}
+
+// event is what travels through the broker, the id lets instances drop duplicates.
+// The members of the Except user don't receive it.
+type event struct {
+	Id     string   `json:"id"`
+	Msg    *Message `json:"msg"`
+	Except int64    `json:"except,omitempty"`
+}
+
+// dedup remembers a bounded number of the latest event ids.
+type dedup struct {
+	ids  []string
+	seen map[string]struct{}
+	next int
+}
+
+func newDedup(cap int) *dedup {
+	return &dedup{
+		ids:  make([]string, cap),
+		seen: make(map[string]struct{}, cap),
+	}
+}
+
+// add reports whether the id is new and remembers it.
+func (d *dedup) add(id string) bool {
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+
+	if old := d.ids[d.next]; old != "" {
+		delete(d.seen, old)
+	}
+
+	d.ids[d.next] = id
+	d.seen[id] = struct{}{}
+	d.next = (d.next + 1) % len(d.ids)
+
+	return true
+}
@@ -0,0 +1,81 @@
+package roomchat
+
+import (
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// EditMessage replaces the text of a message the user has posted to the room, as long as
+// the edit window hasn't passed and the user isn't muted.
+func (h *Hub) EditMessage(room *models.Room, user *models.User, messageId int64, text string) error {
+	msg, err := h.roomMessage(room, messageId)
+	if err != nil {
+		return err
+	}
+
+	if msg.UserId != user.Id {
+		return NotAllowed
+	}
+
+	if time.Since(msg.CreatedAt) > h.editWindow {
+		return EditWindowPassed
+	}
+
+	mutedUntil, err := h.roomStorage.MutedUntil(room.Uuid, user.Id)
+	if err != nil {
+		return err
+	}
+	if time.Now().Before(mutedUntil) {
+		return Muted
+	}
+
+	editedAt := time.Now()
+	if err := h.messageStorage.EditMessage(msg.Id, text, editedAt); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewEditedMessage(user, msg.Id, text, editedAt))
+}
+
+// DeleteMessage deletes a message of the room, authors can delete their own messages and
+// moderators anyone's.
+func (h *Hub) DeleteMessage(room *models.Room, user *models.User, messageId int64) error {
+	msg, err := h.roomMessage(room, messageId)
+	if err != nil {
+		return err
+	}
+
+	if msg.UserId != user.Id {
+		role, err := roomaccess.Role(h.roomStorage, room, user.Id)
+		if err != nil {
+			return err
+		}
+
+		if !role.IsModerator() {
+			return NotAllowed
+		}
+	}
+
+	if err := h.messageStorage.DeleteMessage(msg.Id, time.Now()); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewDeletedMessage(user, msg.Id))
+}
+
+// roomMessage loads a message of the room that hasn't been deleted.
+func (h *Hub) roomMessage(room *models.Room, messageId int64) (*models.Message, error) {
+	msg, err := h.messageStorage.MessageById(messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.RoomUuid != room.Uuid || msg.IsDeleted() {
+		return nil, storage.MessageNotFound
+	}
+
+	return msg, nil
+}
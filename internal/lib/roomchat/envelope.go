@@ -0,0 +1,293 @@
+package roomchat
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+const EnvelopeVersion = 1
+
+const maxClientIdLen = 64
+
+// Envelope is a frame sent by a client:
+//
+//	{"v": 1, "type": "client", "payload": {"text": "hi"}, "client_id": "..."}
+//
+// A missing version is treated as the current one.
+type Envelope struct {
+	Version  int             `json:"v"`
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
+	ClientId string          `json:"client_id"`
+}
+
+// Handler acts on an envelope of one message type. A returned *ClientError is
+// sent back to the member, any other error is logged and reported as ErrInternal.
+type Handler func(m *Member, env *Envelope) error
+
+// handlers registers the message types clients are allowed to send.
+var handlers = map[MessageType]Handler{
+	ClientType: handleClient,
+	KickType:   handleModeration,
+	BanType:    handleModeration,
+	MuteType:   handleModeration,
+	UnmuteType: handleModeration,
+
+	TypingStartType: handleTyping,
+	TypingStopType:  handleTyping,
+	ReadType:        handleRead,
+
+	MessageEditedType:  handleEdit,
+	MessageDeletedType: handleDelete,
+
+	ReactionAddedType:   handleReaction,
+	ReactionRemovedType: handleReaction,
+}
+
+var validate = validator.New()
+
+func dispatch(m *Member, env *Envelope) error {
+	if env.Version == 0 {
+		env.Version = EnvelopeVersion
+	}
+	if env.Version != EnvelopeVersion {
+		return ErrUnsupportedVersion
+	}
+
+	t, ok := ParseMessageType(env.Type)
+	if !ok {
+		return ErrUnknownType
+	}
+
+	handler, ok := handlers[t]
+	if !ok {
+		return ErrUnknownType
+	}
+
+	return handler(m, env)
+}
+
+// decodePayload unmarshals and validates the payload of the envelope.
+func decodePayload(env *Envelope, v interface{}) error {
+	if len(env.Payload) == 0 {
+		return ErrInvalidPayload
+	}
+
+	if err := json.Unmarshal(env.Payload, v); err != nil {
+		return ErrInvalidPayload
+	}
+
+	if err := validate.Struct(v); err != nil {
+		return ErrInvalidPayload
+	}
+
+	return nil
+}
+
+// ClientPayload is a chat message, ReplyTo is the id of the message it answers in a thread.
+type ClientPayload struct {
+	Text    string `json:"text" validate:"required,max=4096"`
+	ReplyTo int64  `json:"reply_to" validate:"min=0"`
+}
+
+// handleClient posts a chat message and acknowledges it to the sender. Retries carrying
+// the same client id are acknowledged again without being posted twice.
+func handleClient(m *Member, env *Envelope) error {
+	if m.room.isMuted(m.user.Id) {
+		return ErrMuted
+	}
+
+	if env.ClientId == "" || len(env.ClientId) > maxClientIdLen {
+		return ErrInvalidClientId
+	}
+
+	var payload ClientPayload
+	if err := decodePayload(env, &payload); err != nil {
+		return err
+	}
+
+	msg := m.NewMessage(payload.Text)
+	msg.ReplyTo = payload.ReplyTo
+
+	msg, err := m.room.Post(msg, env.ClientId)
+	if errors.Is(err, storage.MessageNotFound) {
+		return ErrMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	m.Send(NewAckMessage(env.ClientId, msg))
+
+	if err := m.stopTyping(); err != nil {
+		m.room.hub.log.Error("failed to stop typing", slog.String("room_uuid", m.room.room.Uuid), sl.User(m.user), sl.Err(err))
+	}
+
+	return nil
+}
+
+// handleTyping broadcasts the typing state of the member, typing_start is sent periodically
+// while the user types. Muted members are ignored.
+func handleTyping(m *Member, env *Envelope) error {
+	if m.room.isMuted(m.user.Id) {
+		return nil
+	}
+
+	t, _ := ParseMessageType(env.Type)
+	if t == TypingStartType {
+		return m.startTyping()
+	}
+
+	return m.stopTyping()
+}
+
+// ModerationCommand is sent by moderators to kick, ban, mute or unmute a user.
+// Duration is the length of a mute in seconds.
+type ModerationCommand struct {
+	UserId   int64 `json:"user_id" validate:"required"`
+	Duration int64 `json:"duration" validate:"min=0"`
+}
+
+func handleModeration(m *Member, env *Envelope) error {
+	var cmd ModerationCommand
+	if err := decodePayload(env, &cmd); err != nil {
+		return err
+	}
+
+	t, _ := ParseMessageType(env.Type)
+	if t == MuteType && cmd.Duration <= 0 {
+		return ErrInvalidPayload
+	}
+
+	target, err := m.room.hub.user(cmd.UserId)
+	if errors.Is(err, storage.UserNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	room, hub := m.room.room, m.room.hub
+
+	switch t {
+	case KickType:
+		err = hub.Kick(room, m.user, target)
+	case BanType:
+		err = hub.Ban(room, m.user, target)
+	case MuteType:
+		err = hub.Mute(room, m.user, target, time.Duration(cmd.Duration)*time.Second)
+	case UnmuteType:
+		err = hub.Unmute(room, m.user, target)
+	}
+	if errors.Is(err, NotAllowed) {
+		return ErrNotAllowed
+	}
+
+	return err
+}
+
+type ReadCommand struct {
+	MessageId int64 `json:"message_id" validate:"required,min=1"`
+}
+
+// handleRead marks the messages of the room as read up to the given one.
+func handleRead(m *Member, env *Envelope) error {
+	var cmd ReadCommand
+	if err := decodePayload(env, &cmd); err != nil {
+		return err
+	}
+
+	err := m.room.hub.MarkRead(m.room.room.Uuid, m.user, cmd.MessageId)
+	if errors.Is(err, storage.MessageNotFound) {
+		return ErrMessageNotFound
+	}
+
+	return err
+}
+
+type EditCommand struct {
+	MessageId int64  `json:"message_id" validate:"required,min=1"`
+	Text      string `json:"text" validate:"required,max=4096"`
+}
+
+func handleEdit(m *Member, env *Envelope) error {
+	var cmd EditCommand
+	if err := decodePayload(env, &cmd); err != nil {
+		return err
+	}
+
+	err := m.room.hub.EditMessage(m.room.room, m.user, cmd.MessageId, cmd.Text)
+	return changeError(err)
+}
+
+type DeleteCommand struct {
+	MessageId int64 `json:"message_id" validate:"required,min=1"`
+}
+
+func handleDelete(m *Member, env *Envelope) error {
+	var cmd DeleteCommand
+	if err := decodePayload(env, &cmd); err != nil {
+		return err
+	}
+
+	err := m.room.hub.DeleteMessage(m.room.room, m.user, cmd.MessageId)
+	return changeError(err)
+}
+
+type ReactionCommand struct {
+	MessageId int64  `json:"message_id" validate:"required,min=1"`
+	Emoji     string `json:"emoji" validate:"required"`
+}
+
+// handleReaction puts an emoji on a message of the room or takes it off, repeating either
+// is a no-op.
+func handleReaction(m *Member, env *Envelope) error {
+	if m.room.isMuted(m.user.Id) {
+		return ErrMuted
+	}
+
+	var cmd ReactionCommand
+	if err := decodePayload(env, &cmd); err != nil {
+		return err
+	}
+
+	if !isEmoji(cmd.Emoji) {
+		return ErrInvalidEmoji
+	}
+
+	hub, room := m.room.hub, m.room.room
+
+	var err error
+	if t, _ := ParseMessageType(env.Type); t == ReactionAddedType {
+		err = hub.AddReaction(room, m.user, cmd.MessageId, cmd.Emoji)
+	} else {
+		err = hub.RemoveReaction(room, m.user, cmd.MessageId, cmd.Emoji)
+	}
+	if errors.Is(err, storage.ReactionLimitReached) {
+		return ErrReactionLimit
+	}
+
+	return changeError(err)
+}
+
+// changeError maps the errors of editing and deleting messages to client errors.
+func changeError(err error) error {
+	switch {
+	case errors.Is(err, storage.MessageNotFound):
+		return ErrMessageNotFound
+	case errors.Is(err, NotAllowed):
+		return ErrNotAllowed
+	case errors.Is(err, EditWindowPassed):
+		return ErrEditWindowPassed
+	case errors.Is(err, Muted):
+		return ErrMuted
+	}
+
+	return err
+}
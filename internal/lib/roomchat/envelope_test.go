@@ -0,0 +1,120 @@
+package roomchat
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMessageTypeNames(t *testing.T) {
+	seen := make(map[string]MessageType, len(messageTypes))
+	for typ, name := range messageTypes {
+		if name == "" {
+			t.Errorf("message type %d has no name", typ)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("message types %d and %d are both named %q", typ, other, name)
+		}
+		seen[name] = typ
+
+		parsed, ok := ParseMessageType(name)
+		if !ok || parsed != typ {
+			t.Errorf("ParseMessageType(%q) = %d, %v, want %d", name, parsed, ok, typ)
+		}
+	}
+
+	for _, name := range []string{"", "Client", "unknown", " join"} {
+		if _, ok := ParseMessageType(name); ok {
+			t.Errorf("ParseMessageType(%q) accepted", name)
+		}
+	}
+}
+
+func TestRegistriesNameTheirTypes(t *testing.T) {
+	for typ := range handlers {
+		if _, ok := messageTypes[typ]; !ok {
+			t.Errorf("handled message type %d has no name", typ)
+		}
+	}
+	for typ := range payloads {
+		if _, ok := messageTypes[typ]; !ok {
+			t.Errorf("message type %d with a payload has no name", typ)
+		}
+	}
+}
+
+func TestMessageTypeJSON(t *testing.T) {
+	for typ, name := range messageTypes {
+		data, err := json.Marshal(&typ)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", name, err)
+		}
+		if want := `"` + name + `"`; string(data) != want {
+			t.Errorf("marshal %d = %s, want %s", typ, data, want)
+		}
+
+		var parsed MessageType
+		if err := json.Unmarshal(data, &parsed); err != nil || parsed != typ {
+			t.Errorf("unmarshal %s = %d, %v, want %d", data, parsed, err, typ)
+		}
+	}
+
+	var parsed MessageType
+	if err := json.Unmarshal([]byte(`"unknown"`), &parsed); err == nil {
+		t.Error("unmarshal of an unknown type succeeded")
+	}
+}
+
+func TestDispatchRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		env  Envelope
+		want error
+	}{
+		{"future version", Envelope{Version: EnvelopeVersion + 1, Type: "client"}, ErrUnsupportedVersion},
+		{"negative version", Envelope{Version: -1, Type: "client"}, ErrUnsupportedVersion},
+		{"unknown type", Envelope{Type: "unknown"}, ErrUnknownType},
+		{"missing type", Envelope{}, ErrUnknownType},
+		{"server only type", Envelope{Type: "roster"}, ErrUnknownType},
+		{"server only ack", Envelope{Version: EnvelopeVersion, Type: "ack"}, ErrUnknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if err := dispatch(nil, &env); !errors.Is(err, tt.want) {
+				t.Errorf("dispatch = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		ok      bool
+	}{
+		{"valid", `{"text": "hi"}`, true},
+		{"reply", `{"text": "hi", "reply_to": 3}`, true},
+		{"missing", ``, false},
+		{"malformed", `{"text": `, false},
+		{"empty text", `{"text": ""}`, false},
+		{"negative reply", `{"text": "hi", "reply_to": -1}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &Envelope{Type: "client", Payload: json.RawMessage(tt.payload)}
+
+			var payload ClientPayload
+			err := decodePayload(env, &payload)
+			if tt.ok && err != nil {
+				t.Errorf("decodePayload(%s) = %v", tt.payload, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("decodePayload(%s) = %v, want %v", tt.payload, err, ErrInvalidPayload)
+			}
+		})
+	}
+}
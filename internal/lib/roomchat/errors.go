@@ -4,4 +4,34 @@ import "errors"
 
 var (
 	RoomIsFull = errors.New("room is full")
+	NotAllowed = errors.New("not allowed")
+	Muted      = errors.New("muted")
+
+	EditWindowPassed = errors.New("edit window has passed")
+)
+
+// ClientError rejects a frame sent by a client, it is reported back in an error frame.
+type ClientError struct {
+	Code    string
+	Message string
+}
+
+func (e *ClientError) Error() string {
+	return e.Message
+}
+
+var (
+	ErrMalformedFrame     = &ClientError{Code: "malformed_frame", Message: "frame is not a valid envelope"}
+	ErrUnsupportedVersion = &ClientError{Code: "unsupported_version", Message: "envelope version is not supported"}
+	ErrUnknownType        = &ClientError{Code: "unknown_type", Message: "message type is not supported"}
+	ErrInvalidPayload     = &ClientError{Code: "invalid_payload", Message: "payload is invalid"}
+	ErrInvalidClientId    = &ClientError{Code: "invalid_client_id", Message: "client_id is required and must be at most 64 characters"}
+	ErrMuted              = &ClientError{Code: "muted", Message: "you are muted in this room"}
+	ErrNotAllowed         = &ClientError{Code: "not_allowed", Message: "you are not allowed"}
+	ErrUserNotFound       = &ClientError{Code: "user_not_found", Message: "user is not found"}
+	ErrMessageNotFound    = &ClientError{Code: "message_not_found", Message: "message is not found"}
+	ErrEditWindowPassed   = &ClientError{Code: "edit_window_passed", Message: "message can no longer be edited"}
+	ErrInvalidEmoji       = &ClientError{Code: "invalid_emoji", Message: "reaction must be an emoji"}
+	ErrReactionLimit      = &ClientError{Code: "reaction_limit", Message: "message has too many reactions"}
+	ErrInternal           = &ClientError{Code: "internal", Message: "an unexpected error occurred"}
 )
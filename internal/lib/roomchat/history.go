@@ -0,0 +1,171 @@
+package roomchat
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/types"
+)
+
+// history is a bounded ring of the latest messages posted to a room.
+type history struct {
+	msgs  []*Message
+	start int
+	size  int
+	mu    sync.RWMutex
+}
+
+func newHistory(cap int) *history {
+	return &history{
+		msgs: make([]*Message, cap),
+	}
+}
+
+func (h *history) push(msg *Message) {
+	if len(h.msgs) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	end := (h.start + h.size) % len(h.msgs)
+	h.msgs[end] = msg
+
+	if h.size < len(h.msgs) {
+		h.size++
+	} else {
+		h.start = (h.start + 1) % len(h.msgs)
+	}
+}
+
+// since returns the messages in the ring posted after the message with the given id.
+// ok is false when the ring no longer holds all of them.
+func (h *history) since(id int64) (msgs []*Message, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	msgs = make([]*Message, 0)
+	for i := 0; i < h.size; i++ {
+		if msg := h.at(i); msg.Id > id {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs, h.size > 0 && h.at(0).Id <= id
+}
+
+// edit replaces the message with an edited copy, the message itself may still be
+// on its way to the members.
+func (h *history) edit(id int64, text string, editedAt time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := 0; i < h.size; i++ {
+		j := (h.start + i) % len(h.msgs)
+		if h.msgs[j].Id != id {
+			continue
+		}
+
+		edited := *h.msgs[j]
+		edited.Msg = text
+		edited.EditedAt = &editedAt
+		h.msgs[j] = &edited
+		return
+	}
+}
+
+// reply replaces the root of a thread with a copy counting one more reply.
+func (h *history) reply(rootId int64, repliedAt time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := 0; i < h.size; i++ {
+		j := (h.start + i) % len(h.msgs)
+		if h.msgs[j].Id != rootId {
+			continue
+		}
+
+		replied := *h.msgs[j]
+		replied.Thread = &types.ThreadView{ReplyCount: 1, LastReplyAt: repliedAt}
+		if thread := h.msgs[j].Thread; thread != nil {
+			replied.Thread.ReplyCount += thread.ReplyCount
+		}
+		h.msgs[j] = &replied
+		return
+	}
+}
+
+// react replaces the message with a copy carrying the reaction of the user added or
+// removed, the reactions of the message are copied as well.
+func (h *history) react(id int64, emoji string, userId int64, added bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := 0; i < h.size; i++ {
+		j := (h.start + i) % len(h.msgs)
+		if h.msgs[j].Id != id {
+			continue
+		}
+
+		reacted := *h.msgs[j]
+		reacted.Reactions = make([]*types.ReactionView, 0, len(h.msgs[j].Reactions)+1)
+
+		found := false
+		for _, reaction := range h.msgs[j].Reactions {
+			if reaction.Emoji != emoji {
+				reacted.Reactions = append(reacted.Reactions, reaction)
+				continue
+			}
+
+			found = true
+			userIds := slices.DeleteFunc(slices.Clone(reaction.UserIds), func(uid int64) bool { return uid == userId })
+			if added {
+				userIds = append(userIds, userId)
+			}
+			if len(userIds) > 0 {
+				reacted.Reactions = append(reacted.Reactions, &types.ReactionView{Emoji: emoji, Count: len(userIds), UserIds: userIds})
+			}
+		}
+		if !found && added {
+			reacted.Reactions = append(reacted.Reactions, &types.ReactionView{Emoji: emoji, Count: 1, UserIds: []int64{userId}})
+		}
+
+		h.msgs[j] = &reacted
+		return
+	}
+}
+
+// remove drops the message from the ring, keeping the order of the others.
+func (h *history) remove(id int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := make([]*Message, 0, h.size)
+	for i := 0; i < h.size; i++ {
+		if msg := h.at(i); msg.Id != id {
+			kept = append(kept, msg)
+		}
+	}
+	if len(kept) == h.size {
+		return
+	}
+
+	clear(h.msgs)
+	copy(h.msgs, kept)
+	h.start = 0
+	h.size = len(kept)
+}
+
+// empty reports whether no message has been pushed to the ring yet.
+func (h *history) empty() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.size == 0
+}
+
+func (h *history) at(i int) *Message {
+	return h.msgs[(h.start+i)%len(h.msgs)]
+}
@@ -0,0 +1,87 @@
+package roomchat
+
+import (
+	"slices"
+	"testing"
+)
+
+func newHistoryOf(cap int, ids ...int64) *history {
+	h := newHistory(cap)
+	for _, id := range ids {
+		h.push(&Message{Id: id})
+	}
+	return h
+}
+
+func messageIds(msgs []*Message) []int64 {
+	ids := make([]int64, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.Id)
+	}
+	return ids
+}
+
+func TestHistorySince(t *testing.T) {
+	tests := []struct {
+		name   string
+		cap    int
+		pushed []int64
+		since  int64
+		want   []int64
+		ok     bool
+	}{
+		{"empty", 4, nil, 0, []int64{}, false},
+		{"no capacity", 0, []int64{1, 2}, 0, []int64{}, false},
+		{"all newer", 4, []int64{1, 2, 3}, 1, []int64{2, 3}, true},
+		{"up to date", 4, []int64{1, 2, 3}, 3, []int64{}, true},
+		{"ahead of the ring", 4, []int64{1, 2, 3}, 10, []int64{}, true},
+		{"wrapped", 3, []int64{1, 2, 3, 4, 5}, 4, []int64{5}, true},
+		{"wrapped at the oldest", 3, []int64{1, 2, 3, 4, 5}, 3, []int64{4, 5}, true},
+		{"evicted", 3, []int64{1, 2, 3, 4, 5}, 1, []int64{3, 4, 5}, false},
+		{"out of order", 4, []int64{1, 3, 2, 4}, 1, []int64{3, 2, 4}, true},
+		{"out of order behind the cursor", 4, []int64{1, 3, 2, 4}, 2, []int64{3, 4}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHistoryOf(tt.cap, tt.pushed...)
+
+			msgs, ok := h.since(tt.since)
+			if ok != tt.ok {
+				t.Errorf("since(%d) ok = %v, want %v", tt.since, ok, tt.ok)
+			}
+			if got := messageIds(msgs); !slices.Equal(got, tt.want) {
+				t.Errorf("since(%d) = %v, want %v", tt.since, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistoryPushKeepsLatest(t *testing.T) {
+	h := newHistoryOf(3, 1, 2, 3, 4, 5, 6, 7)
+
+	if h.empty() {
+		t.Fatal("ring is empty after pushes")
+	}
+
+	got := make([]int64, 0, h.size)
+	for i := 0; i < h.size; i++ {
+		got = append(got, h.at(i).Id)
+	}
+	if want := []int64{5, 6, 7}; !slices.Equal(got, want) {
+		t.Errorf("ring holds %v, want %v", got, want)
+	}
+}
+
+func TestHistoryRemove(t *testing.T) {
+	h := newHistoryOf(3, 1, 2, 3, 4)
+	h.remove(3)
+
+	msgs, ok := h.since(2)
+	if !ok {
+		t.Fatal("since(2) reports the ring no longer holds the messages")
+	}
+	if got, want := messageIds(msgs), []int64{4}; !slices.Equal(got, want) {
+		t.Errorf("since(2) = %v, want %v", got, want)
+	}
+}
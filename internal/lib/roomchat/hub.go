@@ -1,31 +1,154 @@
 package roomchat
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
 )
 
+type MessageStorage interface {
+	CreateMessage(roomUuid string, userId int64, text, clientId string, replyTo, threadRoot int64, createdAt time.Time) (*models.Message, error)
+	MessageByClientId(roomUuid string, userId int64, clientId string) (*models.Message, error)
+	ClearClientId(id int64) error
+	MessagesAfter(roomUuid string, after int64, limit int) ([]*models.Message, error)
+	UsersWithIds(ids []int64) (map[int64]*models.User, error)
+	MarkRead(userId int64, roomUuid string, messageId int64) (bool, error)
+	MessageById(id int64) (*models.Message, error)
+	EditMessage(id int64, text string, editedAt time.Time) error
+	DeleteMessage(id int64, deletedAt time.Time) error
+	AddReaction(messageId, userId int64, emoji string, maxEmoji int) (bool, error)
+	RemoveReaction(messageId, userId int64, emoji string) (bool, error)
+	Reactions(messageIds []int64) (map[int64][]*models.Reaction, error)
+	Threads(rootIds []int64) (map[int64]*models.Thread, error)
+	FollowThread(rootId, userId int64) (bool, error)
+	UnfollowThread(rootId, userId int64) (bool, error)
+	UnfollowRoomThreads(roomUuid string, userId int64) error
+	ThreadFollowers(rootId int64) ([]int64, error)
+	UsersWithUsernames(usernames []string) ([]*models.User, error)
+	CreateMentions(messageId int64, roomUuid string, userIds []int64, createdAt time.Time) error
+}
+
+type RoomStorage interface {
+	RoomRole(uuid string, userId int64) (models.Role, error)
+	IsRoomMember(uuid string, userId int64) (bool, error)
+	IsBanned(uuid string, userId int64) (bool, error)
+	RemoveRoomMember(uuid string, userId int64) error
+	BanUser(uuid string, userId int64) error
+	UnbanUser(uuid string, userId int64) error
+	MuteUser(uuid string, userId int64, until time.Time) error
+	UnmuteUser(uuid string, userId int64) error
+	MutedUntil(uuid string, userId int64) (time.Time, error)
+}
+
 type Hub struct {
+	log *slog.Logger
+
 	rooms map[string]*ChatRoom
 	mu    sync.RWMutex
 
-	cap int
+	messageStorage MessageStorage
+	roomStorage    RoomStorage
+	broker         Broker
+	presence       Presence
+
+	cap         int
+	historySize int
+	replayLimit int
+	dedupWindow time.Duration
+	editWindow  time.Duration
+
+	maxReactions int
+
+	typingThrottle time.Duration
+	typingTimeout  time.Duration
+
+	sendQueueSize int
 
 	writeWait  time.Duration
 	pongWait   time.Duration
 	pingPeriod time.Duration
+
+	presenceHeartbeat time.Duration
+}
+
+func NewHub(log *slog.Logger, config *config.Config, messageStorage MessageStorage, roomStorage RoomStorage, broker Broker, presence Presence) (*Hub, error) {
+	h := &Hub{
+		log:            log.With(slog.String("component", "roomchat/hub")),
+		rooms:          make(map[string]*ChatRoom),
+		messageStorage: messageStorage,
+		roomStorage:    roomStorage,
+		broker:         broker,
+		presence:       presence,
+		cap:            config.Chat.Room.Capacity,
+		historySize:    config.Chat.Room.HistorySize,
+		replayLimit:    config.Chat.Room.ReplayLimit,
+		dedupWindow:    config.Chat.DedupWindow,
+		editWindow:     config.Chat.EditWindow,
+		maxReactions:   config.Chat.MaxReactions,
+		typingThrottle: config.Chat.Typing.Throttle,
+		typingTimeout:  config.Chat.Typing.Timeout,
+		sendQueueSize:  config.Chat.SendQueueSize,
+		writeWait:      config.Chat.WriteWait,
+		pongWait:       config.Chat.PongWait,
+		pingPeriod:     config.Chat.PingPeriod,
+
+		presenceHeartbeat: config.Chat.Presence.Heartbeat,
+	}
+
+	if _, err := broker.Subscribe(usersChannel, h.deliverUser); err != nil {
+		return nil, err
+	}
+
+	if _, err := broker.Subscribe(roomChannel("*"), h.deliverRoom); err != nil {
+		return nil, err
+	}
+
+	go h.heartbeat()
+
+	return h, nil
 }
 
-func NewHub(config *config.Config) *Hub {
-	return &Hub{
-		rooms:      make(map[string]*ChatRoom),
-		cap:        config.Chat.Room.Capacity,
-		writeWait:  config.Chat.WriteWait,
-		pongWait:   config.Chat.PongWait,
-		pingPeriod: config.Chat.PingPeriod,
+// heartbeat periodically refreshes the presence of the rooms open on this instance.
+func (h *Hub) heartbeat() {
+	ticker := time.NewTicker(h.presenceHeartbeat)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.mu.RLock()
+		roomUuids := make([]string, 0, len(h.rooms))
+		for roomUuid := range h.rooms {
+			roomUuids = append(roomUuids, roomUuid)
+		}
+		h.mu.RUnlock()
+
+		if err := h.presence.Heartbeat(roomUuids); err != nil {
+			h.log.Error("failed to refresh the presence", sl.Err(err))
+		}
+	}
+}
+
+// Join adds the user to the chat of the room, see ChatRoom.NewMember.
+func (h *Hub) Join(r *models.Room, conn *websocket.Conn, user *models.User, since int64) (*Member, error) {
+	for {
+		room := h.GetOrCreateRoom(r)
+
+		m, err := room.join(conn, user, &since)
+		if errors.Is(err, errRoomClosed) {
+			continue
+		}
+
+		return m, err
 	}
 }
 
@@ -38,17 +161,188 @@ func (h *Hub) GetOrCreateRoom(r *models.Room) *ChatRoom {
 		return room
 	}
 
-	room = NewRoom(r, h)
-
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if room, ok := h.rooms[r.Uuid]; ok {
+		return room
+	}
+
+	room = NewRoom(r, h)
 	h.rooms[r.Uuid] = room
-	h.mu.Unlock()
 
 	return room
 }
 
-func (h *Hub) DeleteRoom(r *models.Room) {
+func (h *Hub) detach(room *ChatRoom) {
 	h.mu.Lock()
-	delete(h.rooms, r.Uuid)
-	h.mu.Unlock()
+	defer h.mu.Unlock()
+
+	if h.rooms[room.room.Uuid] == room {
+		delete(h.rooms, room.room.Uuid)
+	}
+}
+
+// Broadcast publishes the message to the members of the room on every instance,
+// whether or not this one has a chat open for it.
+func (h *Hub) Broadcast(roomUuid string, msg *Message) error {
+	return h.publish(roomUuid, event{Id: uuid.NewString(), Msg: msg})
+}
+
+func (h *Hub) publish(roomUuid string, e event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return h.broker.Publish(roomChannel(roomUuid), payload)
+}
+
+// DisconnectUser closes the connections of the user in every room on every instance.
+func (h *Hub) DisconnectUser(userId int64) error {
+	return h.publishUser(userEvent{UserId: userId, Revoke: true})
+}
+
+func (h *Hub) publishUser(e userEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return h.broker.Publish(usersChannel, payload)
+}
+
+// deliverUser handles a user event received from the broker.
+func (h *Hub) deliverUser(_ string, payload []byte) {
+	var e userEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		h.log.Error("failed to decode user event", sl.Err(err))
+		return
+	}
+
+	h.mu.RLock()
+	rooms := make([]*ChatRoom, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		room.deliverUser(&e)
+	}
+}
+
+// deliverRoom routes a room event received from the broker to the chat of the room,
+// the events of rooms without a chat open on this instance are dropped.
+func (h *Hub) deliverRoom(channel string, payload []byte) {
+	roomUuid, ok := roomOfChannel(channel)
+	if !ok {
+		return
+	}
+
+	h.mu.RLock()
+	room, ok := h.rooms[roomUuid]
+	h.mu.RUnlock()
+
+	if ok {
+		room.deliver(payload)
+	}
+}
+
+// storedAfter loads the messages of the room posted right after the message with the given
+// id, truncated is true when there are more than the replay limit.
+func (h *Hub) storedAfter(roomUuid string, since int64) (msgs []*Message, truncated bool, err error) {
+	stored, err := h.messageStorage.MessagesAfter(roomUuid, since, h.replayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(stored) > h.replayLimit {
+		stored, truncated = stored[:h.replayLimit], true
+	}
+
+	author_ids := make([]int64, 0)
+	ids := make([]int64, 0, len(stored))
+	for _, msg := range stored {
+		author_ids = append(author_ids, msg.UserId)
+		ids = append(ids, msg.Id)
+	}
+
+	authors, err := h.messageStorage.UsersWithIds(author_ids)
+	if err != nil {
+		return nil, false, err
+	}
+
+	reactions, err := h.messageStorage.Reactions(ids)
+	if err != nil {
+		return nil, false, err
+	}
+
+	threads, err := h.messageStorage.Threads(ids)
+	if err != nil {
+		return nil, false, err
+	}
+
+	msgs = make([]*Message, 0, len(stored))
+	for _, msg := range stored {
+		msgs = append(msgs, NewStoredMessage(msg, authors[msg.UserId], reactions[msg.Id], threads[msg.Id]))
+	}
+
+	return msgs, truncated, nil
+}
+
+// MarkRead moves the read cursor of the user in the room up to the message and broadcasts
+// the receipt, a message older than the cursor is ignored.
+func (h *Hub) MarkRead(roomUuid string, user *models.User, messageId int64) error {
+	moved, err := h.messageStorage.MarkRead(user.Id, roomUuid, messageId)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return nil
+	}
+
+	return h.Broadcast(roomUuid, NewReadMessage(user, messageId))
+}
+
+// Roster returns the users online in the room on every instance, ordered by id.
+func (h *Hub) Roster(roomUuid string) ([]*models.User, error) {
+	ids, err := h.presence.OnlineUsers(roomUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := h.messageStorage.UsersWithIds(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := make([]*models.User, 0, len(users))
+	for _, user := range users {
+		roster = append(roster, user)
+	}
+	slices.SortFunc(roster, func(a, b *models.User) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	return roster, nil
+}
+
+// OnlineCounts returns the number of users online in each of the rooms.
+func (h *Hub) OnlineCounts(roomUuids []string) (map[string]int, error) {
+	return h.presence.OnlineCounts(roomUuids)
+}
+
+// user loads a single user by id.
+func (h *Hub) user(id int64) (*models.User, error) {
+	users, err := h.messageStorage.UsersWithIds([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := users[id]
+	if !ok {
+		return nil, storage.UserNotFound
+	}
+
+	return user, nil
 }
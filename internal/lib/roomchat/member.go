@@ -1,13 +1,27 @@
 package roomchat
 
 import (
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
 )
 
+// Close codes of members removed by the server.
+const (
+	// CloseSlowConsumer is sent to members evicted for not keeping up with the room.
+	CloseSlowConsumer = 4008
+	CloseKicked       = 4009
+	CloseBanned       = 4010
+	// CloseSessionRevoked is sent to members whose user has logged out everywhere.
+	CloseSessionRevoked = 4011
+)
+
 type Member struct {
 	room *ChatRoom
 
@@ -15,57 +29,161 @@ type Member struct {
 	isClosed bool
 	mu       sync.Mutex
 
+	// closeFrame is left for writePump by disconnect, which can't wait for a slow client.
+	closeFrame []byte
+
+	// send queues the messages for writePump, the only writer of the connection
+	// once the member has joined.
+	send chan *Message
+	done chan struct{}
+
 	user *models.User
+
+	// replayed holds the ids of the messages sent on join and cursor the highest of them,
+	// both guarded by the room lock once the member has been added. Messages aren't
+	// delivered in id order, so broadcast skips exactly the replayed ones.
+	replayed map[int64]struct{}
+	cursor   int64
+
+	typing typing
 }
 
 func NewMember(conn *websocket.Conn, user *models.User, room *ChatRoom) *Member {
-	m := &Member{
+	return &Member{
 		room:     room,
 		conn:     conn,
 		user:     user,
 		isClosed: false,
+		send:     make(chan *Message, room.hub.sendQueueSize),
+		done:     make(chan struct{}),
+		replayed: make(map[int64]struct{}),
 	}
+}
 
-	ticker := time.NewTicker(room.hub.pingPeriod)
-	go func() {
-		defer func() {
-			ticker.Stop()
+// Send queues the message for the member. A member whose queue is full is disconnected
+// rather than slowing down the room.
+func (m *Member) Send(msg *Message) {
+	select {
+	case <-m.done:
+		return
+	default:
+	}
 
-			m.mu.Lock()
-			if !m.isClosed {
-				m.conn.Close()
-				m.isClosed = true
-			}
-			m.mu.Unlock()
-		}()
+	select {
+	case m.send <- msg:
+	default:
+		m.evict()
+	}
+}
 
-		for range ticker.C {
-			m.conn.SetWriteDeadline(time.Now().Add(m.room.hub.writeWait))
+func (m *Member) evict() {
+	m.room.hub.log.Info("evicting slow consumer", slog.String("room_uuid", m.room.room.Uuid), sl.User(m.user))
+
+	if m.disconnect(CloseSlowConsumer, "slow consumer") {
+		metrics.Add(metricSlowConsumerEvictions, 1)
+	}
+}
+
+// disconnect closes the member with the code, it reports whether it was still open.
+// Callers may hold the room lock, so the close frame is written and the connection is
+// closed by writePump instead.
+func (m *Member) disconnect(code int, reason string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isClosed {
+		return false
+	}
+
+	m.closeFrame = websocket.FormatCloseMessage(code, reason)
+	m.isClosed = true
+	close(m.done)
+
+	return true
+}
+
+// writePump writes the queued messages and pings the client until the connection is closed.
+func (m *Member) writePump() {
+	ticker := time.NewTicker(m.room.hub.pingPeriod)
+	defer func() {
+		ticker.Stop()
+
+		m.mu.Lock()
+		closeFrame := m.closeFrame
+		m.closeFrame = nil
+		m.close()
+		m.mu.Unlock()
+
+		if closeFrame != nil {
+			m.conn.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(m.room.hub.writeWait))
+			m.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		default:
+		}
 
+		select {
+		case msg := <-m.send:
+			m.conn.SetWriteDeadline(time.Now().Add(m.room.hub.writeWait))
+			if err := m.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			m.conn.SetWriteDeadline(time.Now().Add(m.room.hub.writeWait))
 			if err := m.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+		case <-m.done:
+			return
 		}
-	}()
-
-	return m
+	}
 }
 
-func (m *Member) WriteJSON(msg *Message) {
+// replay writes the messages straight to the connection of a member that hasn't
+// been added to the room yet, before writePump is started. The stored messages among
+// them are recorded as replayed.
+func (m *Member) replay(msgs []*Message) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.isClosed {
-		return
+	for _, msg := range msgs {
+		m.conn.SetWriteDeadline(time.Now().Add(m.room.hub.writeWait))
+		if err := m.conn.WriteJSON(msg); err != nil {
+			m.close()
+			return err
+		}
+		m.markReplayed(msg)
 	}
 
-	m.conn.SetWriteDeadline(time.Now().Add(m.room.hub.writeWait))
-	if err := m.conn.WriteJSON(msg); err != nil {
-		m.close()
+	return nil
+}
+
+func (m *Member) markReplayed(msg *Message) {
+	if msg.Id <= 0 {
+		return
 	}
+
+	m.replayed[msg.Id] = struct{}{}
+	m.cursor = max(m.cursor, msg.Id)
 }
 
+// discard closes the connection of a member that hasn't been added to the room.
+func (m *Member) discard() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.close()
+}
+
+// ReadPump reads the connection until it is closed, the member leaves the room afterwards.
 func (m *Member) ReadPump() {
+	defer m.room.Remove(m)
+
 	m.conn.SetReadDeadline(time.Now().Add(m.room.hub.pongWait))
 	m.conn.SetPongHandler(func(string) error { m.conn.SetReadDeadline(time.Now().Add(m.room.hub.pongWait)); return nil })
 
@@ -79,17 +197,35 @@ func (m *Member) ReadPump() {
 		}
 
 		if msgType == websocket.CloseMessage {
-			m.mu.Lock()
-			if !m.isClosed {
-				m.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-				m.close()
-			}
-			m.mu.Unlock()
+			m.disconnect(websocket.CloseNormalClosure, "")
 			return
 		}
 
-		m.room.Broadcast(m.NewMessage(string(msg)))
+		m.handle(msg)
+	}
+}
+
+// handle dispatches a frame received from the client, rejected frames are answered
+// with an error frame instead of being broadcast.
+func (m *Member) handle(rcv []byte) {
+	var env Envelope
+	if err := json.Unmarshal(rcv, &env); err != nil {
+		m.Send(NewErrorMessage(ErrMalformedFrame, ""))
+		return
 	}
+
+	err := dispatch(m, &env)
+	if err == nil {
+		return
+	}
+
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		m.room.hub.log.Error("failed to handle the frame", slog.String("room_uuid", m.room.room.Uuid), slog.String("type", env.Type), sl.User(m.user), sl.Err(err))
+		clientErr = ErrInternal
+	}
+
+	m.Send(NewErrorMessage(clientErr, env.ClientId))
 }
 
 func (m *Member) close() {
@@ -99,7 +235,7 @@ func (m *Member) close() {
 
 	m.conn.Close()
 	m.isClosed = true
-	m.room.Remove(m)
+	close(m.done)
 }
 
 func (m *Member) NewMessage(rcv string) *Message {
@@ -0,0 +1,89 @@
+package roomchat
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// maxMentions bounds the number of users a single message can mention.
+const maxMentions = 20
+
+// notifyMentions records the users mentioned in the message who can enter the room, banned
+// users aren't, and sends them the message in every room they are chatting in.
+func (h *Hub) notifyMentions(room *models.Room, msg *Message) error {
+	usernames := parseMentions(msg.Msg)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := h.messageStorage.UsersWithUsernames(usernames)
+	if err != nil {
+		return err
+	}
+
+	mentioned := make([]int64, 0, len(users))
+	for _, user := range users {
+		if user.Id == msg.From.Id {
+			continue
+		}
+
+		granted, err := roomaccess.Granted(h.roomStorage, room, user)
+		if err != nil {
+			return err
+		}
+		if granted {
+			mentioned = append(mentioned, user.Id)
+		}
+	}
+	if len(mentioned) == 0 {
+		return nil
+	}
+
+	if err := h.messageStorage.CreateMentions(msg.Id, room.Uuid, mentioned, msg.CreatedAt); err != nil {
+		return err
+	}
+
+	mention := NewMentionMessage(room.Uuid, msg)
+	for _, userId := range mentioned {
+		if err := h.publishUser(userEvent{UserId: userId, Msg: mention}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseMentions returns the distinct usernames written as @username in the text. An @
+// right after a letter or a digit, as in an email address, doesn't start a mention.
+func parseMentions(text string) []string {
+	usernames := make([]string, 0)
+	seen := make(map[string]bool)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes) && len(usernames) < maxMentions; i++ {
+		if runes[i] != '@' || (i > 0 && isUsernameRune(runes[i-1])) {
+			continue
+		}
+
+		j := i + 1
+		for j < len(runes) && isUsernameRune(runes[j]) {
+			j++
+		}
+
+		username := strings.TrimRight(string(runes[i+1:j]), ".-")
+		if username != "" && !seen[username] {
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+		i = j - 1
+	}
+
+	return usernames
+}
+
+func isUsernameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
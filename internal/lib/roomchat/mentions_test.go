@@ -0,0 +1,51 @@
+package roomchat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"no mentions", "hello there", []string{}},
+		{"single", "hi @alice", []string{"alice"}},
+		{"several", "@alice and @bob_2", []string{"alice", "bob_2"}},
+		{"repeated", "@alice @bob @alice", []string{"alice", "bob"}},
+		{"email address", "mail me at alice@example.com", []string{}},
+		{"trailing punctuation", "thanks @alice. and @bob-", []string{"alice", "bob"}},
+		{"inner dots and dashes", "@first.last @a-b", []string{"first.last", "a-b"}},
+		{"followed by punctuation", "(@alice), @bob!", []string{"alice", "bob"}},
+		{"lone at", "@ @@ @.", []string{}},
+		{"double at", "@@alice", []string{"alice"}},
+		{"unicode", "@josé hi", []string{"josé"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentions(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("parseMentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMentionsLimit(t *testing.T) {
+	words := make([]string, 0, maxMentions+5)
+	for i := 0; i < maxMentions+5; i++ {
+		words = append(words, fmt.Sprintf("@user%d", i))
+	}
+
+	got := parseMentions(strings.Join(words, " "))
+	if len(got) != maxMentions {
+		t.Fatalf("got %d mentions, want %d", len(got), maxMentions)
+	}
+	if got[maxMentions-1] != fmt.Sprintf("user%d", maxMentions-1) {
+		t.Errorf("last mention is %q, want the first %d in order", got[maxMentions-1], maxMentions)
+	}
+}
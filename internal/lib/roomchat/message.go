@@ -14,29 +14,150 @@ type MessageType int
 const JoinType MessageType = 0
 const LeaveType MessageType = 1
 const ClientType MessageType = 2
+const ErrorType MessageType = 3
+const AckType MessageType = 4
+const KickType MessageType = 5
+const BanType MessageType = 6
+const MuteType MessageType = 7
+const UnmuteType MessageType = 8
+const RosterType MessageType = 9
+const TypingStartType MessageType = 10
+const TypingStopType MessageType = 11
+const ReadType MessageType = 12
+const MessageEditedType MessageType = 13
+const MessageDeletedType MessageType = 14
+const ReactionAddedType MessageType = 15
+const ReactionRemovedType MessageType = 16
+const ThreadReplyType MessageType = 17
+const MentionType MessageType = 18
+const ReplayTruncatedType MessageType = 19
 
-func (t *MessageType) String() string {
-	switch *t {
-	case JoinType:
-		return "join"
-	case LeaveType:
-		return "leave"
-	case ClientType:
-		return "client"
+// messageTypes names every message type on the wire.
+var messageTypes = map[MessageType]string{
+	JoinType:   "join",
+	LeaveType:  "leave",
+	ClientType: "client",
+	ErrorType:  "error",
+	AckType:    "ack",
+	KickType:   "kick",
+	BanType:    "ban",
+	MuteType:   "mute",
+	UnmuteType: "unmute",
+	RosterType: "roster",
+
+	TypingStartType: "typing_start",
+	TypingStopType:  "typing_stop",
+	ReadType:        "read",
+
+	MessageEditedType:  "message_edited",
+	MessageDeletedType: "message_deleted",
+
+	ReactionAddedType:   "reaction_added",
+	ReactionRemovedType: "reaction_removed",
+
+	ThreadReplyType: "thread_reply",
+	MentionType:     "mention",
+
+	ReplayTruncatedType: "replay_truncated",
+}
+
+// payloads creates the payloads of message types that carry one, so that
+// messages received from the broker are decoded back into them.
+var payloads = map[MessageType]func() interface{}{
+	JoinType:   func() interface{} { return &PresencePayload{} },
+	LeaveType:  func() interface{} { return &PresencePayload{} },
+	RosterType: func() interface{} { return &RosterPayload{} },
+	ReadType:   func() interface{} { return &ReadPayload{} },
+	ErrorType:  func() interface{} { return &ErrorPayload{} },
+	AckType:    func() interface{} { return &AckPayload{} },
+	KickType:   func() interface{} { return &ModerationPayload{} },
+	BanType:    func() interface{} { return &ModerationPayload{} },
+	MuteType:   func() interface{} { return &ModerationPayload{} },
+	UnmuteType: func() interface{} { return &ModerationPayload{} },
+
+	MessageEditedType:  func() interface{} { return &EditPayload{} },
+	MessageDeletedType: func() interface{} { return &DeletePayload{} },
+
+	ReactionAddedType:   func() interface{} { return &ReactionPayload{} },
+	ReactionRemovedType: func() interface{} { return &ReactionPayload{} },
+
+	ThreadReplyType: func() interface{} { return &ThreadReplyPayload{} },
+	MentionType:     func() interface{} { return &MentionPayload{} },
+
+	ReplayTruncatedType: func() interface{} { return &ReplayTruncatedPayload{} },
+}
+
+func ParseMessageType(name string) (MessageType, bool) {
+	for t, n := range messageTypes {
+		if n == name {
+			return t, true
+		}
 	}
 
-	return ""
+	return 0, false
+}
+
+func (t *MessageType) String() string {
+	return messageTypes[*t]
 }
 
 func (t *MessageType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(t.String())
 }
 
+func (t *MessageType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	parsed, ok := ParseMessageType(name)
+	if !ok {
+		return fmt.Errorf("unknown message type %q", name)
+	}
+	*t = parsed
+
+	return nil
+}
+
 type Message struct {
+	Id        int64           `json:"id,omitempty"`
 	Type      MessageType     `json:"type"`
-	Msg       string          `json:"message"`
+	Msg       string          `json:"message,omitempty"`
 	From      *types.UserView `json:"from,omitempty"`
+	Payload   interface{}     `json:"payload,omitempty"`
 	CreatedAt time.Time       `json:"created_at"`
+	EditedAt  *time.Time      `json:"edited_at,omitempty"`
+
+	// ReplyTo and ThreadRoot are set on replies, Thread on messages that have been replied to.
+	ReplyTo    int64             `json:"reply_to,omitempty"`
+	ThreadRoot int64             `json:"thread_root,omitempty"`
+	Thread     *types.ThreadView `json:"thread,omitempty"`
+
+	Reactions []*types.ReactionView `json:"reactions,omitempty"`
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	aux := struct {
+		*message
+		Payload json.RawMessage `json:"payload"`
+	}{message: (*message)(m)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	m.Payload = nil
+	if newPayload, ok := payloads[m.Type]; ok && len(aux.Payload) > 0 {
+		payload := newPayload()
+		if err := json.Unmarshal(aux.Payload, payload); err != nil {
+			return err
+		}
+		m.Payload = payload
+	}
+
+	return nil
 }
 
 func NewMessage(msg string, from *models.User) *Message {
@@ -48,20 +169,276 @@ func NewMessage(msg string, from *models.User) *Message {
 	}
 }
 
+// NewStoredMessage restores a client message from the room history, thread is nil unless
+// the message has replies.
+func NewStoredMessage(msg *models.Message, from *models.User, reactions []*models.Reaction, thread *models.Thread) *Message {
+	m := &Message{
+		Id:         msg.Id,
+		Type:       ClientType,
+		Msg:        msg.Text,
+		From:       types.NewUser(from),
+		CreatedAt:  msg.CreatedAt,
+		ReplyTo:    msg.ReplyTo,
+		ThreadRoot: msg.ThreadRoot,
+	}
+
+	if thread != nil {
+		m.Thread = types.NewThread(thread)
+	}
+
+	if len(reactions) > 0 {
+		m.Reactions = types.NewReactions(reactions)
+	}
+
+	if !msg.EditedAt.IsZero() {
+		m.EditedAt = &msg.EditedAt
+	}
+
+	return m
+}
+
+// PresencePayload is the user who has come online or gone offline in the room.
+type PresencePayload struct {
+	User *types.UserView `json:"user"`
+}
+
+// NewJoinMessage adds the user to the rosters of the members.
 func NewJoinMessage(u *models.User) *Message {
 	return &Message{
 		Type:      JoinType,
-		Msg:       fmt.Sprintf("%s has joined the chat", u.Username),
-		From:      nil,
+		Payload:   &PresencePayload{User: types.NewUser(u)},
 		CreatedAt: time.Now(),
 	}
 }
 
+// NewLeaveMessage removes the user from the rosters of the members.
 func NewLeaveMessage(u *models.User) *Message {
 	return &Message{
 		Type:      LeaveType,
-		Msg:       fmt.Sprintf("%s has left the chat", u.Username),
-		From:      nil,
+		Payload:   &PresencePayload{User: types.NewUser(u)},
+		CreatedAt: time.Now(),
+	}
+}
+
+// NewTypingMessage tells the other members that the user has started or stopped typing,
+// it is never stored.
+func NewTypingMessage(t MessageType, u *models.User) *Message {
+	return &Message{
+		Type:      t,
+		From:      types.NewUser(u),
+		CreatedAt: time.Now(),
+	}
+}
+
+// ReadPayload is the latest message the user has read.
+type ReadPayload struct {
+	MessageId int64 `json:"message_id"`
+}
+
+// NewReadMessage is the read receipt of the user.
+func NewReadMessage(u *models.User, messageId int64) *Message {
+	return &Message{
+		Type:      ReadType,
+		From:      types.NewUser(u),
+		Payload:   &ReadPayload{MessageId: messageId},
+		CreatedAt: time.Now(),
+	}
+}
+
+// EditPayload is the new text of the message with the given id.
+type EditPayload struct {
+	MessageId int64     `json:"message_id"`
+	Text      string    `json:"text"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// NewEditedMessage tells the members that the author has edited the message.
+func NewEditedMessage(u *models.User, messageId int64, text string, editedAt time.Time) *Message {
+	return &Message{
+		Type: MessageEditedType,
+		From: types.NewUser(u),
+		Payload: &EditPayload{
+			MessageId: messageId,
+			Text:      text,
+			EditedAt:  editedAt,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// DeletePayload is the id of the deleted message, By is its author or a moderator.
+type DeletePayload struct {
+	MessageId int64           `json:"message_id"`
+	By        *types.UserView `json:"by"`
+}
+
+func NewDeletedMessage(by *models.User, messageId int64) *Message {
+	return &Message{
+		Type: MessageDeletedType,
+		Payload: &DeletePayload{
+			MessageId: messageId,
+			By:        types.NewUser(by),
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// ReactionPayload is the emoji the sender has put on or taken off the message.
+type ReactionPayload struct {
+	MessageId int64  `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+func NewReactionMessage(t MessageType, u *models.User, messageId int64, emoji string) *Message {
+	return &Message{
+		Type: t,
+		From: types.NewUser(u),
+		Payload: &ReactionPayload{
+			MessageId: messageId,
+			Emoji:     emoji,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// ThreadReplyPayload is a reply posted in a thread the user follows, sent to the user in
+// the other rooms the user is chatting in.
+type ThreadReplyPayload struct {
+	RoomUuid string   `json:"room_uuid"`
+	Reply    *Message `json:"reply"`
+}
+
+func NewThreadReplyMessage(roomUuid string, reply *Message) *Message {
+	return &Message{
+		Type: ThreadReplyType,
+		Payload: &ThreadReplyPayload{
+			RoomUuid: roomUuid,
+			Reply:    reply,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// MentionPayload is a message of the room that mentions the user it is sent to.
+type MentionPayload struct {
+	RoomUuid string   `json:"room_uuid"`
+	Message  *Message `json:"message"`
+}
+
+func NewMentionMessage(roomUuid string, msg *Message) *Message {
+	return &Message{
+		Type: MentionType,
+		Payload: &MentionPayload{
+			RoomUuid: roomUuid,
+			Message:  msg,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// ReplayTruncatedPayload ends a replay that has been cut at the replay limit, the messages
+// posted after the one with id After are to be fetched from the history of the room.
+type ReplayTruncatedPayload struct {
+	After int64 `json:"after"`
+}
+
+func NewReplayTruncatedMessage(after int64) *Message {
+	return &Message{
+		Type:      ReplayTruncatedType,
+		Payload:   &ReplayTruncatedPayload{After: after},
+		CreatedAt: time.Now(),
+	}
+}
+
+// RosterPayload lists the users online in the room.
+type RosterPayload struct {
+	Users []*types.UserView `json:"users"`
+}
+
+// NewRosterMessage is sent to a new member, join and leave messages keep it up to date.
+func NewRosterMessage(users []*models.User) *Message {
+	views := make([]*types.UserView, 0, len(users))
+	for _, u := range users {
+		views = append(views, types.NewUser(u))
+	}
+
+	return &Message{
+		Type:      RosterType,
+		Payload:   &RosterPayload{Users: views},
+		CreatedAt: time.Now(),
+	}
+}
+
+type ErrorPayload struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ClientId string `json:"client_id,omitempty"`
+}
+
+// NewErrorMessage reports a rejected frame back to its sender.
+func NewErrorMessage(err *ClientError, clientId string) *Message {
+	return &Message{
+		Type: ErrorType,
+		Payload: &ErrorPayload{
+			Code:     err.Code,
+			Message:  err.Message,
+			ClientId: clientId,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+type AckPayload struct {
+	ClientId  string    `json:"client_id"`
+	MessageId int64     `json:"message_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewAckMessage confirms to the sender that its message has been stored.
+func NewAckMessage(clientId string, msg *Message) *Message {
+	return &Message{
+		Type: AckType,
+		Payload: &AckPayload{
+			ClientId:  clientId,
+			MessageId: msg.Id,
+			CreatedAt: msg.CreatedAt,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// ModerationPayload describes a moderation action, Until is set for mutes.
+type ModerationPayload struct {
+	User  *types.UserView `json:"user"`
+	By    *types.UserView `json:"by"`
+	Until *time.Time      `json:"until,omitempty"`
+}
+
+func NewKickMessage(u, by *models.User) *Message {
+	return newModerationMessage(KickType, fmt.Sprintf("%s has been kicked by %s", u.Username, by.Username), u, by, nil)
+}
+
+func NewBanMessage(u, by *models.User) *Message {
+	return newModerationMessage(BanType, fmt.Sprintf("%s has been banned by %s", u.Username, by.Username), u, by, nil)
+}
+
+func NewMuteMessage(u, by *models.User, until time.Time) *Message {
+	return newModerationMessage(MuteType, fmt.Sprintf("%s has been muted by %s", u.Username, by.Username), u, by, &until)
+}
+
+func NewUnmuteMessage(u, by *models.User) *Message {
+	return newModerationMessage(UnmuteType, fmt.Sprintf("%s has been unmuted by %s", u.Username, by.Username), u, by, nil)
+}
+
+func newModerationMessage(t MessageType, msg string, u, by *models.User, until *time.Time) *Message {
+	return &Message{
+		Type: t,
+		Msg:  msg,
+		Payload: &ModerationPayload{
+			User:  types.NewUser(u),
+			By:    types.NewUser(by),
+			Until: until,
+		},
 		CreatedAt: time.Now(),
 	}
 }
@@ -0,0 +1,8 @@
+package roomchat
+
+import "expvar"
+
+// metrics are published by expvar under the "roomchat" key.
+var metrics = expvar.NewMap("roomchat")
+
+const metricSlowConsumerEvictions = "slow_consumer_evictions"
@@ -0,0 +1,92 @@
+package roomchat
+
+import (
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// Kick disconnects the target from the room, they are free to join again.
+func (h *Hub) Kick(room *models.Room, by, target *models.User) error {
+	if err := h.authorize(room, by, target); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewKickMessage(target, by))
+}
+
+// Ban disconnects the target and keeps them out of the room until unbanned.
+// Access granted through the room password and the followed threads are revoked as well.
+func (h *Hub) Ban(room *models.Room, by, target *models.User) error {
+	if err := h.authorize(room, by, target); err != nil {
+		return err
+	}
+
+	if err := h.roomStorage.BanUser(room.Uuid, target.Id); err != nil {
+		return err
+	}
+
+	if err := h.roomStorage.RemoveRoomMember(room.Uuid, target.Id); err != nil {
+		return err
+	}
+
+	if err := h.messageStorage.UnfollowRoomThreads(room.Uuid, target.Id); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewBanMessage(target, by))
+}
+
+func (h *Hub) Unban(room *models.Room, by, target *models.User) error {
+	if err := h.authorize(room, by, target); err != nil {
+		return err
+	}
+
+	return h.roomStorage.UnbanUser(room.Uuid, target.Id)
+}
+
+// Mute stops the target from posting to the room for the duration.
+func (h *Hub) Mute(room *models.Room, by, target *models.User, d time.Duration) error {
+	if err := h.authorize(room, by, target); err != nil {
+		return err
+	}
+
+	until := time.Now().Add(d)
+	if err := h.roomStorage.MuteUser(room.Uuid, target.Id, until); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewMuteMessage(target, by, until))
+}
+
+func (h *Hub) Unmute(room *models.Room, by, target *models.User) error {
+	if err := h.authorize(room, by, target); err != nil {
+		return err
+	}
+
+	if err := h.roomStorage.UnmuteUser(room.Uuid, target.Id); err != nil {
+		return err
+	}
+
+	return h.Broadcast(room.Uuid, NewUnmuteMessage(target, by))
+}
+
+// authorize checks that the user outranks the target in the room.
+func (h *Hub) authorize(room *models.Room, by, target *models.User) error {
+	role, err := roomaccess.Role(h.roomStorage, room, by.Id)
+	if err != nil {
+		return err
+	}
+
+	targetRole, err := roomaccess.Role(h.roomStorage, room, target.Id)
+	if err != nil {
+		return err
+	}
+
+	if !role.CanModerate(targetRole) {
+		return NotAllowed
+	}
+
+	return nil
+}
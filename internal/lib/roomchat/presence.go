@@ -0,0 +1,91 @@
+package roomchat
+
+import "sync"
+
+// Presence counts the open connections of users in rooms, on every instance when shared.
+type Presence interface {
+	// AddPresence reports whether it is the first connection of the user in the room.
+	AddPresence(roomUuid string, userId int64) (bool, error)
+	// RemovePresence reports whether it was the last connection of the user in the room.
+	RemovePresence(roomUuid string, userId int64) (bool, error)
+	OnlineUsers(roomUuid string) ([]int64, error)
+	OnlineCounts(roomUuids []string) (map[string]int, error)
+	// Heartbeat keeps alive the presence of the rooms open on this instance, the presence
+	// kept by an instance that stops sending it expires.
+	Heartbeat(roomUuids []string) error
+}
+
+// LocalPresence keeps the presence within the process, it goes along with the LocalBroker.
+type LocalPresence struct {
+	rooms map[string]map[int64]int
+	mu    sync.RWMutex
+}
+
+func NewLocalPresence() *LocalPresence {
+	return &LocalPresence{
+		rooms: make(map[string]map[int64]int),
+	}
+}
+
+func (p *LocalPresence) AddPresence(roomUuid string, userId int64) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.rooms[roomUuid]; !ok {
+		p.rooms[roomUuid] = make(map[int64]int)
+	}
+	p.rooms[roomUuid][userId]++
+
+	return p.rooms[roomUuid][userId] == 1, nil
+}
+
+func (p *LocalPresence) RemovePresence(roomUuid string, userId int64) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	users, ok := p.rooms[roomUuid]
+	if !ok || users[userId] == 0 {
+		return false, nil
+	}
+
+	users[userId]--
+	if users[userId] > 0 {
+		return false, nil
+	}
+
+	delete(users, userId)
+	if len(users) == 0 {
+		delete(p.rooms, roomUuid)
+	}
+
+	return true, nil
+}
+
+func (p *LocalPresence) OnlineUsers(roomUuid string) ([]int64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ids := make([]int64, 0, len(p.rooms[roomUuid]))
+	for id := range p.rooms[roomUuid] {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (p *LocalPresence) OnlineCounts(roomUuids []string) (map[string]int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	counts := make(map[string]int, len(roomUuids))
+	for _, uuid := range roomUuids {
+		counts[uuid] = len(p.rooms[uuid])
+	}
+
+	return counts, nil
+}
+
+// Heartbeat does nothing, the presence goes away along with the process.
+func (p *LocalPresence) Heartbeat(roomUuids []string) error {
+	return nil
+}
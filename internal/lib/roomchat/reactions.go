@@ -0,0 +1,94 @@
+package roomchat
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// maxEmojiLen bounds an emoji in bytes, enough for sequences joining several emoji.
+const maxEmojiLen = 32
+
+// AddReaction puts the emoji of the user on a message of the room and broadcasts it,
+// a reaction the user has already put is ignored.
+func (h *Hub) AddReaction(room *models.Room, user *models.User, messageId int64, emoji string) error {
+	msg, err := h.roomMessage(room, messageId)
+	if err != nil {
+		return err
+	}
+
+	added, err := h.messageStorage.AddReaction(msg.Id, user.Id, emoji, h.maxReactions)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return nil
+	}
+
+	return h.Broadcast(room.Uuid, NewReactionMessage(ReactionAddedType, user, msg.Id, emoji))
+}
+
+// RemoveReaction takes the emoji of the user off a message of the room and broadcasts it.
+func (h *Hub) RemoveReaction(room *models.Room, user *models.User, messageId int64, emoji string) error {
+	msg, err := h.roomMessage(room, messageId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := h.messageStorage.RemoveReaction(msg.Id, user.Id, emoji)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return nil
+	}
+
+	return h.Broadcast(room.Uuid, NewReactionMessage(ReactionRemovedType, user, msg.Id, emoji))
+}
+
+// isEmoji reports whether the text is a single emoji, possibly a sequence joined with
+// zero width joiners, modifiers and variation selectors.
+func isEmoji(text string) bool {
+	if text == "" || len(text) > maxEmojiLen || !utf8.ValidString(text) {
+		return false
+	}
+
+	keycap := strings.ContainsRune(text, 0x20E3)
+
+	pictographs := 0
+	for _, r := range text {
+		switch {
+		case isPictograph(r), keycap && strings.ContainsRune("0123456789#*", r):
+			pictographs++
+		case r == 0x200D, r == 0xFE0F, r == 0x20E3, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+			// joiners, variation selectors, keycaps, skin tones and tags
+		default:
+			return false
+		}
+	}
+
+	return pictographs > 0
+}
+
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r >= 0x2190 && r <= 0x21FF:
+		return true
+	}
+
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+
+	return false
+}
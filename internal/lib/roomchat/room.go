@@ -1,17 +1,33 @@
 package roomchat
 
 import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
 	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
 )
 
+const dedupSize = 1024
+
+var errRoomClosed = errors.New("room is closed")
+
 type ChatRoom struct {
-	hub  *Hub
-	room *models.Room
+	hub     *Hub
+	room    *models.Room
+	history *history
+	seen    *dedup
 
 	members map[*Member]bool
+	mutes   map[int64]time.Time
+	closed  bool
 	mu      sync.RWMutex
 
 	cap int
@@ -21,38 +37,271 @@ func NewRoom(room *models.Room, hub *Hub) *ChatRoom {
 	return &ChatRoom{
 		hub:     hub,
 		room:    room,
+		history: newHistory(hub.historySize),
+		seen:    newDedup(dedupSize),
 		members: make(map[*Member]bool),
+		mutes:   make(map[int64]time.Time),
 		cap:     hub.cap,
 	}
 }
 
-func (r *ChatRoom) Broadcast(msg *Message) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+// Broadcast publishes the message to the members of the room on every instance.
+func (r *ChatRoom) Broadcast(msg *Message) error {
+	return r.hub.Broadcast(r.room.Uuid, msg)
+}
+
+// BroadcastExcept publishes the message to the members of the room except the ones of the user.
+func (r *ChatRoom) BroadcastExcept(msg *Message, userId int64) error {
+	return r.hub.publish(r.room.Uuid, event{Id: uuid.NewString(), Msg: msg, Except: userId})
+}
+
+// Post saves a client message to the room history and broadcasts it with the id it has
+// been stored under. A message the author has already sent to the room with the same client
+// id within the dedup window is returned as stored without being posted again, past the
+// window the client id is taken over by the new message. A message with
+// ReplyTo set is posted in the thread of the message it answers, see Hub.notifyThread,
+// the users it mentions are notified with Hub.notifyMentions.
+func (r *ChatRoom) Post(msg *Message, clientId string) (*Message, error) {
+	prev, err := r.hub.messageStorage.MessageByClientId(r.room.Uuid, msg.From.Id, clientId)
+	if err != nil && !errors.Is(err, storage.MessageNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		if time.Since(prev.CreatedAt) < r.hub.dedupWindow {
+			return storedAs(msg, prev), nil
+		}
+
+		if err := r.hub.messageStorage.ClearClientId(prev.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	var root *models.Message
+	if msg.ReplyTo != 0 {
+		parent, err := r.hub.roomMessage(r.room, msg.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+
+		if root, err = r.hub.threadRoot(r.room, parent); err != nil {
+			return nil, err
+		}
+		msg.ThreadRoot = root.Id
+	}
+
+	stored, err := r.hub.messageStorage.CreateMessage(r.room.Uuid, msg.From.Id, msg.Msg, clientId, msg.ReplyTo, msg.ThreadRoot, msg.CreatedAt)
+	if errors.Is(err, storage.MessageExists) {
+		// A retry of the message has been stored in the meantime.
+		prev, err := r.hub.messageStorage.MessageByClientId(r.room.Uuid, msg.From.Id, clientId)
+		if err != nil {
+			return nil, err
+		}
+		return storedAs(msg, prev), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg.Id = stored.Id
+
+	if err := r.Broadcast(msg); err != nil {
+		return nil, err
+	}
+
+	if root != nil {
+		if err := r.hub.notifyThread(r.room, root, msg); err != nil {
+			r.hub.log.Error("failed to notify the thread followers", slog.String("room_uuid", r.room.Uuid), slog.Int64("thread_root", root.Id), sl.Err(err))
+		}
+	}
 
-	r.broadcast(msg)
+	if err := r.hub.notifyMentions(r.room, msg); err != nil {
+		r.hub.log.Error("failed to notify the mentioned users", slog.String("room_uuid", r.room.Uuid), slog.Int64("message_id", msg.Id), sl.Err(err))
+	}
+
+	return msg, nil
+}
+
+// storedAs fills the message in with the stored one it duplicates.
+func storedAs(msg *Message, stored *models.Message) *Message {
+	msg.Id = stored.Id
+	msg.CreatedAt = stored.CreatedAt
+	msg.ReplyTo = stored.ReplyTo
+	msg.ThreadRoot = stored.ThreadRoot
+	return msg
 }
 
-func (r *ChatRoom) NewMember(conn *websocket.Conn, user *models.User) (*Member, error) {
+// NewMember joins the user to the room. A positive since is the id of the last message
+// the user has seen, everything posted after it is sent before the member is added. When
+// more than the replay limit has been missed, only the messages right after since are sent
+// followed by a replay truncated message, the client pages through the rest of them with
+// the history of the room.
+func (r *ChatRoom) NewMember(conn *websocket.Conn, user *models.User, since int64) (*Member, error) {
+	return r.join(conn, user, &since)
+}
+
+// join is NewMember with since moved past the messages that have been replayed, so that
+// a retry in another room doesn't send them twice.
+//
+// The replay is written without holding the room lock, a slow client only delays itself.
+// Messages delivered meanwhile are picked up from the ring of recent messages and queued
+// once the lock is taken, the ones already replayed are skipped by broadcast.
+func (r *ChatRoom) join(conn *websocket.Conn, user *models.User, since *int64) (*Member, error) {
+	mutedUntil, err := r.hub.roomStorage.MutedUntil(r.room.Uuid, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	closed, full := r.closed, r.isFull()
+	r.mu.RUnlock()
+
+	if closed {
+		return nil, errRoomClosed
+	}
+	if full {
+		return nil, RoomIsFull
+	}
+
+	from := *since
 	m := NewMember(conn, user, r)
+	m.cursor = *since
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	for {
+		truncated := false
+		if *since > 0 {
+			missed, more, err := r.missed(m.cursor)
+			if err != nil {
+				m.discard()
+				return nil, err
+			}
+
+			if err := m.replay(missed); err != nil {
+				return nil, err
+			}
+			*since = m.cursor
+
+			if more {
+				if err := m.replay([]*Message{NewReplayTruncatedMessage(m.cursor)}); err != nil {
+					return nil, err
+				}
+				truncated = true
+			}
+		}
+
+		r.mu.Lock()
+
+		// Past a truncated replay the member picks up from the messages delivered from
+		// now on, the ones in between are left for the client to page through.
+		if *since <= 0 || truncated || r.catchUp(m, from) {
+			break
+		}
+
+		r.mu.Unlock()
+	}
+
+	if r.closed {
+		r.mu.Unlock()
+		return nil, errRoomClosed
+	}
 
 	if err := r.add(m); err != nil {
+		r.mu.Unlock()
+		m.discard()
 		return nil, err
 	}
+	go m.writePump()
 
-	r.broadcast(NewJoinMessage(m.user))
+	if time.Now().Before(mutedUntil) {
+		r.mutes[user.Id] = mutedUntil
+	}
+
+	r.mu.Unlock()
+
+	r.arrive(m)
 	return m, nil
 }
 
+// catchUp queues the messages delivered to the room while the member was being replayed
+// to, it reports false when the ring doesn't hold all of them or they don't fit the queue
+// and the member has to be replayed to again. Messages posted after from that arrived
+// out of order behind the replayed ones are queued as well. The caller holds the room lock.
+func (r *ChatRoom) catchUp(m *Member, from int64) bool {
+	if _, ok := r.history.since(m.cursor); !ok && !r.history.empty() {
+		return false
+	}
+
+	gap, _ := r.history.since(from)
+	gap = slices.DeleteFunc(gap, func(msg *Message) bool {
+		_, replayed := m.replayed[msg.Id]
+		return replayed
+	})
+	if len(gap) > cap(m.send) {
+		return false
+	}
+
+	for _, msg := range gap {
+		m.send <- msg
+		m.markReplayed(msg)
+	}
+
+	return true
+}
+
 func (r *ChatRoom) Remove(m *Member) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	removed := r.remove(m)
+	r.mu.Unlock()
+
+	if removed {
+		if err := m.stopTyping(); err != nil {
+			r.hub.log.Error("failed to stop typing", slog.String("room_uuid", r.room.Uuid), sl.User(m.user), sl.Err(err))
+		}
+		r.depart(m)
+	}
+}
+
+// arrive counts the member as online and sends it the roster, the other members are told
+// about the user unless it is already connected elsewhere.
+func (r *ChatRoom) arrive(m *Member) {
+	first, err := r.hub.presence.AddPresence(r.room.Uuid, m.user.Id)
+	if err != nil {
+		r.hub.log.Error("failed to add the presence", slog.String("room_uuid", r.room.Uuid), sl.User(m.user), sl.Err(err))
+	}
+
+	roster, err := r.hub.Roster(r.room.Uuid)
+	if err != nil {
+		r.hub.log.Error("failed to get the roster", slog.String("room_uuid", r.room.Uuid), sl.Err(err))
+	} else {
+		m.Send(NewRosterMessage(roster))
+	}
+
+	if first {
+		r.announce(NewJoinMessage(m.user))
+	}
+}
+
+// depart drops the presence of the member, the user is announced as gone with its last connection.
+func (r *ChatRoom) depart(m *Member) {
+	last, err := r.hub.presence.RemovePresence(r.room.Uuid, m.user.Id)
+	if err != nil {
+		r.hub.log.Error("failed to remove the presence", slog.String("room_uuid", r.room.Uuid), sl.User(m.user), sl.Err(err))
+		return
+	}
+
+	if last {
+		r.announce(NewLeaveMessage(m.user))
+	}
+}
 
-	r.remove(m)
-	r.broadcast(NewLeaveMessage(m.user))
+// missed returns the messages posted after the message with the given id, falling back
+// to the storage when they are no longer in the ring of recent messages. The ring is
+// dropped together with the room, since it stops receiving events once detached.
+// truncated is true when the storage holds more of them than the replay limit.
+func (r *ChatRoom) missed(since int64) (msgs []*Message, truncated bool, err error) {
+	if msgs, ok := r.history.since(since); ok {
+		return msgs, false, nil
+	}
+
+	return r.hub.storedAfter(r.room.Uuid, since)
 }
 
 func (r *ChatRoom) add(m *Member) error {
@@ -65,20 +314,144 @@ func (r *ChatRoom) add(m *Member) error {
 	return nil
 }
 
-func (r *ChatRoom) remove(m *Member) {
+func (r *ChatRoom) remove(m *Member) bool {
 	if _, ok := r.members[m]; !ok {
-		return
+		return false
 	}
 
 	delete(r.members, m)
 	if r.isEmpty() {
-		r.hub.DeleteRoom(r.room)
+		r.close()
+	}
+
+	return true
+}
+
+// close detaches the emptied room from the hub, the events of the room are no longer
+// routed to it. Members joining afterwards get a fresh room.
+func (r *ChatRoom) close() {
+	r.closed = true
+	r.hub.detach(r)
+}
+
+// deliver handles an event received from the broker.
+func (r *ChatRoom) deliver(payload []byte) {
+	var e event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		r.hub.log.Error("failed to decode room event", slog.String("room_uuid", r.room.Uuid), sl.Err(err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || e.Msg == nil || !r.seen.add(e.Id) {
+		return
+	}
+
+	if e.Msg.Id > 0 {
+		r.history.push(e.Msg)
+		if e.Msg.ThreadRoot > 0 {
+			r.history.reply(e.Msg.ThreadRoot, e.Msg.CreatedAt)
+		}
+	}
+
+	r.broadcast(e.Msg, e.Except)
+	r.apply(e.Msg)
+}
+
+// deliverUser sends the message of the user event to the members of the user.
+func (r *ChatRoom) deliverUser(e *userEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.room.Uuid == e.Skip {
+		return
+	}
+
+	for m := range r.members {
+		if m.user.Id != e.UserId {
+			continue
+		}
+
+		if e.Msg != nil {
+			m.Send(e.Msg)
+		}
+		if e.Revoke {
+			m.disconnect(CloseSessionRevoked, "session revoked")
+		}
+	}
+}
+
+// apply carries out the side effects of a delivered message on the local members
+// and the ring of recent messages.
+func (r *ChatRoom) apply(msg *Message) {
+	switch payload := msg.Payload.(type) {
+	case *ModerationPayload:
+		r.moderate(msg.Type, payload)
+	case *EditPayload:
+		r.history.edit(payload.MessageId, payload.Text, payload.EditedAt)
+	case *DeletePayload:
+		r.history.remove(payload.MessageId)
+	case *ReactionPayload:
+		if msg.From != nil {
+			r.history.react(payload.MessageId, payload.Emoji, msg.From.Id, msg.Type == ReactionAddedType)
+		}
+	}
+}
+
+func (r *ChatRoom) moderate(t MessageType, payload *ModerationPayload) {
+	if payload.User == nil {
+		return
+	}
+
+	switch t {
+	case KickType:
+		r.disconnect(payload.User.Id, CloseKicked, "kicked")
+	case BanType:
+		r.disconnect(payload.User.Id, CloseBanned, "banned")
+	case MuteType:
+		if payload.Until != nil {
+			r.mutes[payload.User.Id] = *payload.Until
+		}
+	case UnmuteType:
+		delete(r.mutes, payload.User.Id)
+	}
+}
+
+func (r *ChatRoom) disconnect(userId int64, code int, reason string) {
+	for m := range r.members {
+		if m.user.Id == userId {
+			m.disconnect(code, reason)
+		}
+	}
+}
+
+func (r *ChatRoom) isMuted(userId int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	until, ok := r.mutes[userId]
+	return ok && time.Now().Before(until)
+}
+
+func (r *ChatRoom) announce(msg *Message) {
+	if err := r.Broadcast(msg); err != nil {
+		r.hub.log.Error("failed to broadcast the message", slog.String("room_uuid", r.room.Uuid), sl.Err(err))
 	}
 }
 
-func (r *ChatRoom) broadcast(msg *Message) {
+func (r *ChatRoom) broadcast(msg *Message, except int64) {
 	for m := range r.members {
-		go m.WriteJSON(msg)
+		if _, ok := m.replayed[msg.Id]; ok && msg.Id > 0 {
+			delete(m.replayed, msg.Id)
+			continue
+		}
+		if except != 0 && m.user.Id == except {
+			continue
+		}
+
+		m.Send(msg)
 	}
 }
 
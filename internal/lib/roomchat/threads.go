@@ -0,0 +1,110 @@
+package roomchat
+
+import (
+	"github.com/guluzadehh/go_chat/internal/lib/roomaccess"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// FollowThread subscribes the user to the replies in the thread of the message, following
+// a reply follows its thread.
+func (h *Hub) FollowThread(room *models.Room, user *models.User, messageId int64) error {
+	msg, err := h.roomMessage(room, messageId)
+	if err != nil {
+		return err
+	}
+
+	root, err := h.threadRoot(room, msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.messageStorage.FollowThread(root.Id, user.Id)
+	return err
+}
+
+// UnfollowThread stops sending the replies in the thread of the message to the user,
+// threads of deleted messages can be unfollowed as well.
+func (h *Hub) UnfollowThread(room *models.Room, user *models.User, messageId int64) error {
+	msg, err := h.messageStorage.MessageById(messageId)
+	if err != nil {
+		return err
+	}
+
+	if msg.RoomUuid != room.Uuid {
+		return storage.MessageNotFound
+	}
+
+	root, err := h.threadRoot(room, msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.messageStorage.UnfollowThread(root.Id, user.Id)
+	return err
+}
+
+// threadRoot loads the first message of the thread the message of the room belongs to,
+// a message that isn't a reply is its own root. The root may have been deleted since.
+func (h *Hub) threadRoot(room *models.Room, msg *models.Message) (*models.Message, error) {
+	if !msg.IsReply() {
+		return msg, nil
+	}
+
+	root, err := h.messageStorage.MessageById(msg.ThreadRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.RoomUuid != room.Uuid {
+		return nil, storage.MessageNotFound
+	}
+
+	return root, nil
+}
+
+// notifyThread makes the author of the reply, and the one of the root while it can still
+// enter the room, follow the thread and sends the reply to the followers in the other
+// rooms they are chatting in, the members of the room itself already get it with the
+// room stream. Followers who can no longer enter the room, banned ones included, are skipped.
+func (h *Hub) notifyThread(room *models.Room, root *models.Message, reply *Message) error {
+	if _, err := h.messageStorage.FollowThread(root.Id, reply.From.Id); err != nil {
+		return err
+	}
+
+	granted, err := roomaccess.Granted(h.roomStorage, room, &models.User{Id: root.UserId})
+	if err != nil {
+		return err
+	}
+	if granted {
+		if _, err := h.messageStorage.FollowThread(root.Id, root.UserId); err != nil {
+			return err
+		}
+	}
+
+	followers, err := h.messageStorage.ThreadFollowers(root.Id)
+	if err != nil {
+		return err
+	}
+
+	msg := NewThreadReplyMessage(room.Uuid, reply)
+	for _, userId := range followers {
+		if userId == reply.From.Id {
+			continue
+		}
+
+		granted, err := roomaccess.Granted(h.roomStorage, room, &models.User{Id: userId})
+		if err != nil {
+			return err
+		}
+		if !granted {
+			continue
+		}
+
+		if err := h.publishUser(userEvent{UserId: userId, Msg: msg, Skip: room.Uuid}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
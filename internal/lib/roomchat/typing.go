@@ -0,0 +1,87 @@
+package roomchat
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/sl"
+)
+
+// typing is the typing state of a member, it expires when no stop arrives in time.
+type typing struct {
+	active bool
+	sentAt time.Time
+	timer  *time.Timer
+	mu     sync.Mutex
+}
+
+// startTyping marks the member as typing. A start within the throttle interval of the
+// previous one only pushes the expiry back.
+func (m *Member) startTyping() error {
+	t := &m.typing
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(m.room.hub.typingTimeout, func() { m.expireTyping(timer) })
+	t.timer = timer
+
+	if t.active && time.Since(t.sentAt) < m.room.hub.typingThrottle {
+		return nil
+	}
+
+	t.active = true
+	t.sentAt = time.Now()
+
+	return m.room.BroadcastExcept(NewTypingMessage(TypingStartType, m.user), m.user.Id)
+}
+
+// stopTyping tells the other members that the member is no longer typing.
+func (m *Member) stopTyping() error {
+	t := &m.typing
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return m.clearTyping()
+}
+
+// expireTyping stops the typing started along with the timer, unless a later start has
+// replaced the timer.
+func (m *Member) expireTyping(timer *time.Timer) {
+	t := &m.typing
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != timer {
+		return
+	}
+
+	if err := m.clearTyping(); err != nil {
+		m.room.hub.log.Error("failed to expire typing", slog.String("room_uuid", m.room.room.Uuid), sl.User(m.user), sl.Err(err))
+	}
+}
+
+// clearTyping must be called with the typing lock held.
+func (m *Member) clearTyping() error {
+	t := &m.typing
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+
+	if !t.active {
+		return nil
+	}
+	t.active = false
+
+	return m.room.BroadcastExcept(NewTypingMessage(TypingStopType, m.user), m.user.Id)
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guluzadehh/go_chat/internal/config"
+	"github.com/guluzadehh/go_chat/internal/lib/auth"
+	"github.com/guluzadehh/go_chat/internal/lib/jwt"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+type TokenStorage interface {
+	SaveRefreshToken(family, jti string, ttl time.Duration) error
+	TokenVersion(userId int64) (int64, error)
+}
+
+// Start signs the user in, it sets the refresh cookie and returns the access token.
+func Start(w http.ResponseWriter, config *config.Config, tokenStorage TokenStorage, user *models.User) (string, error) {
+	const op = "lib.session.Start"
+
+	version, err := tokenStorage.TokenVersion(user.Id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	access, err := jwt.AccessToken(user, version, config)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	family, jti := uuid.NewString(), uuid.NewString()
+
+	refresh, err := jwt.RefreshToken(user, family, jti, version, config)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tokenStorage.SaveRefreshToken(family, jti, config.JWT.Refresh.Expire); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	encoded, err := auth.Encrypt(refresh, []byte(config.JWT.Refresh.EncryptSecretKey))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	auth.SetRefreshCookie(w, encoded, config)
+
+	return access, nil
+}
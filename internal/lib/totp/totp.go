@@ -0,0 +1,124 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// Codes follow RFC 6238 with the defaults authenticator apps expect.
+const (
+	period = 30
+	digits = 6
+	// skew is the number of time steps a code is accepted before and after its own.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI is the provisioning uri of the secret, rendered as a QR code by the clients.
+func URI(secret, issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(digits))
+	params.Set("period", strconv.Itoa(period))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", url.PathEscape(issuer+":"+account), params.Encode())
+}
+
+// Validate checks the code against the secret at t and returns the time step it belongs to.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+
+	step := t.Unix() / period
+	for i := int64(-skew); i <= skew; i++ {
+		if hmac.Equal([]byte(generate(key, step+i)), []byte(code)) {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+func generate(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
+
+// RecoveryCodes generates n one-time recovery codes formatted as xxxxx-xxxxx.
+func RecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode hashes the recovery code for storage, codes are random enough for
+// a fast hash.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", "")
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
+
+type StepStorage interface {
+	UseTotpStep(userId, step int64) (bool, error)
+}
+
+// Verify checks the code of the user and uses up its time step, so that an intercepted
+// code can't be replayed.
+func Verify(stepStorage StepStorage, user *models.User, code string) (bool, error) {
+	if user.TotpSecret == "" {
+		return false, nil
+	}
+
+	step, ok := Validate(user.TotpSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return stepStorage.UseTotpStep(user.Id, step)
+}
@@ -0,0 +1,149 @@
+package totp
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of RFC 6238 appendix B, "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes, the 6 digit ones are their last 6 digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestGenerateRFC6238(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range rfcVectors {
+		if got := generate(key, tt.unix/period); got != tt.code {
+			t.Errorf("generate at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, tt := range rfcVectors {
+		at := time.Unix(tt.unix, 0)
+
+		step, ok := Validate(rfcSecret, tt.code, at)
+		if !ok {
+			t.Errorf("Validate at %d rejected %s", tt.unix, tt.code)
+			continue
+		}
+		if want := tt.unix / period; step != want {
+			t.Errorf("Validate at %d returned step %d, want %d", tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	const unix = 1111111111
+	const code = "050471"
+	codeStep := int64(unix / period)
+
+	tests := []struct {
+		name  string
+		shift int64
+		ok    bool
+	}{
+		{"same step", 0, true},
+		{"one step later", 1, true},
+		{"one step earlier", -1, true},
+		{"two steps later", 2, false},
+		{"two steps earlier", -2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := time.Unix((codeStep+tt.shift)*period, 0)
+
+			step, ok := Validate(rfcSecret, code, at)
+			if ok != tt.ok {
+				t.Fatalf("Validate ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && step != codeStep {
+				t.Errorf("Validate step = %d, want %d", step, codeStep)
+			}
+		})
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"short code", rfcSecret, "28708"},
+		{"long code", rfcSecret, "2870820"},
+		{"invalid secret", "not base32!", "287082"},
+		{"wrong code", rfcSecret, "287083"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, at); ok {
+				t.Errorf("Validate(%q, %q) accepted", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateLowercaseSecret(t *testing.T) {
+	if _, ok := Validate("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", time.Unix(59, 0)); !ok {
+		t.Error("Validate rejected a lowercase secret")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+
+	codes, err := RecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q isn't formatted as xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q is repeated", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghij")
+
+	for _, code := range []string{"abcdefghij", "ABCDE-FGHIJ", "  abcde-fghij\n"} {
+		if got := HashRecoveryCode(code); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the canonical code", code)
+		}
+	}
+
+	if HashRecoveryCode("abcde-fghik") == want {
+		t.Error("different codes hash the same")
+	}
+}
@@ -1,9 +1,26 @@
 package models
 
+import (
+	"log/slog"
+	"time"
+)
+
 type User struct {
 	Id       int64
 	Username string
 	Password string
+	Email    string
+	IsAdmin  bool
+
+	// TotpSecret is set once the user enrolls in two-factor authentication, which is
+	// enabled after the first code is confirmed.
+	TotpSecret  string
+	TotpEnabled bool
+
+	DisplayName string
+	AvatarUrl   string
+	Bio         string
+	CreatedAt   time.Time
 }
 
 type Room struct {
@@ -11,8 +28,144 @@ type Room struct {
 	Name     string
 	Password string
 	OwnerId  int64
+
+	// PeerId is the second user of a direct conversation, the first one is the owner.
+	PeerId int64
 }
 
 func (r *Room) IsPrivate() bool {
 	return len(r.Password) > 0
 }
+
+// IsDirect reports whether the room is a direct conversation between two users.
+func (r *Room) IsDirect() bool {
+	return r.PeerId != 0
+}
+
+// IsParticipant reports whether the user is one of the two users of a direct conversation.
+func (r *Room) IsParticipant(userId int64) bool {
+	return r.IsDirect() && (r.OwnerId == userId || r.PeerId == userId)
+}
+
+// Peer returns the other user of a direct conversation.
+func (r *Room) Peer(userId int64) int64 {
+	if r.OwnerId == userId {
+		return r.PeerId
+	}
+
+	return r.OwnerId
+}
+
+// LogValue keeps the password out of the logs.
+func (r Room) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("uuid", r.Uuid),
+		slog.String("name", r.Name),
+		slog.Bool("is_private", r.IsPrivate()),
+		slog.Int64("owner_id", r.OwnerId),
+		slog.Int64("peer_id", r.PeerId),
+	)
+}
+
+type Role string
+
+const (
+	RoleOwner     Role = "owner"
+	RoleModerator Role = "moderator"
+	RoleMember    Role = "member"
+)
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 2
+	case RoleModerator:
+		return 1
+	}
+
+	return 0
+}
+
+// IsModerator reports whether the role can moderate the room.
+func (r Role) IsModerator() bool {
+	return r.rank() >= RoleModerator.rank()
+}
+
+// CanModerate reports whether a user with the role can kick, ban or mute a user with the target role.
+func (r Role) CanModerate(target Role) bool {
+	return r.rank() >= RoleModerator.rank() && r.rank() > target.rank()
+}
+
+type Message struct {
+	Id        int64
+	RoomUuid  string
+	UserId    int64
+	Text      string
+	ClientId  string
+	CreatedAt time.Time
+
+	// ReplyTo is the message this one answers and ThreadRoot the first message of its
+	// thread, both are zero for messages of the main stream.
+	ReplyTo    int64
+	ThreadRoot int64
+
+	// EditedAt and DeletedAt are zero unless the message has been edited or deleted,
+	// deleted messages keep their id but lose their text.
+	EditedAt  time.Time
+	DeletedAt time.Time
+}
+
+func (m *Message) IsDeleted() bool {
+	return !m.DeletedAt.IsZero()
+}
+
+func (m *Message) IsReply() bool {
+	return m.ThreadRoot != 0
+}
+
+// Thread sums up the replies to a message that isn't deleted.
+type Thread struct {
+	ReplyCount  int
+	LastReplyAt time.Time
+}
+
+// Mention is a message of a room that mentions the user, ReadAt is zero until the user
+// has read it.
+type Mention struct {
+	UserId  int64
+	Message *Message
+	ReadAt  time.Time
+}
+
+func (m *Mention) IsRead() bool {
+	return !m.ReadAt.IsZero()
+}
+
+// Reaction is an emoji put on a message along with the users who have put it.
+type Reaction struct {
+	Emoji   string
+	UserIds []int64
+}
+
+type Invite struct {
+	Id        string
+	RoomUuid  string
+	CreatedBy int64
+	Username  string
+	MaxUses   int
+	Uses      int
+	ExpiresAt time.Time
+}
+
+// IsExhausted reports whether the invite has been used up, a zero MaxUses means unlimited uses.
+func (i *Invite) IsExhausted() bool {
+	return i.MaxUses > 0 && i.Uses >= i.MaxUses
+}
+
+// LoginLockout is a username or a client ip whose login attempts are locked until Until.
+type LoginLockout struct {
+	Kind     string
+	Subject  string
+	Failures int64
+	Until    time.Time
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// directNamespace seeds the uuids of direct conversations, so that a pair of users always
+// gets the same room.
+var directNamespace = uuid.MustParse("5b0e2a7c-3f4d-4c1e-9a8b-6d2f1e0c7a93")
+
+// DirectRoom returns the direct conversation between the two users, creating it on first use.
+func (s *Storage) DirectRoom(userId, peerId int64) (*models.Room, error) {
+	const op = "storage.redis.DirectRoom"
+
+	ctx := context.Background()
+
+	ownerId, peerId := min(userId, peerId), max(userId, peerId)
+
+	room := &models.Room{
+		Uuid:    directRoomUuid(ownerId, peerId),
+		OwnerId: ownerId,
+		PeerId:  peerId,
+	}
+
+	// Both users may open the conversation at once, they write the same fields.
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, roomKey(room.Uuid), map[string]interface{}{
+			"name":     room.Name,
+			"password": room.Password,
+			"owner_id": room.OwnerId,
+			"peer_id":  room.PeerId,
+		})
+		pipe.SAdd(ctx, userRoomsKey(ownerId), room.Uuid)
+		pipe.SAdd(ctx, userRoomsKey(peerId), room.Uuid)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return room, nil
+}
+
+// DirectRooms returns the direct conversations the user takes part in, looked up in the
+// index of the user rather than among every room.
+func (s *Storage) DirectRooms(userId int64) ([]*models.Room, error) {
+	const op = "storage.redis.DirectRooms"
+
+	rooms, err := s.UserRooms(userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	direct := make([]*models.Room, 0)
+	for _, room := range rooms {
+		if room.IsDirect() && room.IsParticipant(userId) {
+			direct = append(direct, room)
+		}
+	}
+
+	return direct, nil
+}
+
+func directRoomUuid(ownerId, peerId int64) string {
+	return uuid.NewSHA1(directNamespace, []byte(fmt.Sprintf("dm:%d:%d", ownerId, peerId))).String()
+}
@@ -0,0 +1,216 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+func (s *Storage) CreateInvite(roomUuid string, createdBy int64, username string, maxUses int, expiresAt time.Time) (*models.Invite, error) {
+	const op = "storage.redis.CreateInvite"
+
+	ctx := context.Background()
+
+	invite := &models.Invite{
+		Id:        uuid.NewString(),
+		RoomUuid:  roomUuid,
+		CreatedBy: createdBy,
+		Username:  username,
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+	}
+
+	key := inviteKey(roomUuid, invite.Id)
+
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"created_by": invite.CreatedBy,
+			"username":   invite.Username,
+			"max_uses":   invite.MaxUses,
+			"uses":       0,
+			"expires_at": invite.ExpiresAt.Unix(),
+		})
+		pipe.ExpireAt(ctx, key, invite.ExpiresAt)
+		pipe.SAdd(ctx, roomInvitesKey(roomUuid), invite.Id)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invite, nil
+}
+
+// Invites returns the invites of the room which haven't expired yet.
+func (s *Storage) Invites(roomUuid string) ([]*models.Invite, error) {
+	const op = "storage.redis.Invites"
+
+	ctx := context.Background()
+
+	ids, err := s.cli.SMembers(ctx, roomInvitesKey(roomUuid)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	invites := make([]*models.Invite, 0, len(ids))
+	for _, id := range ids {
+		invite, err := s.InviteById(roomUuid, id)
+		if errors.Is(err, storage.InviteNotFound) {
+			s.cli.SRem(ctx, roomInvitesKey(roomUuid), id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		invites = append(invites, invite)
+	}
+
+	return invites, nil
+}
+
+func (s *Storage) InviteById(roomUuid, id string) (*models.Invite, error) {
+	const op = "storage.redis.InviteById"
+
+	ctx := context.Background()
+
+	data, err := s.cli.HGetAll(ctx, inviteKey(roomUuid, id)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, storage.InviteNotFound)
+	}
+
+	invite, err := parseInvite(roomUuid, id, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invite, nil
+}
+
+func (s *Storage) RevokeInvite(roomUuid, id string) error {
+	const op = "storage.redis.RevokeInvite"
+
+	ctx := context.Background()
+
+	res, err := s.cli.Del(ctx, inviteKey(roomUuid, id)).Result()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.cli.SRem(ctx, roomInvitesKey(roomUuid), id).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if res == 0 {
+		return fmt.Errorf("%s: %w", op, storage.InviteNotFound)
+	}
+
+	return nil
+}
+
+// redeemInviteScript counts a use of the invite unless it is gone, expired or used up. It
+// returns the number of uses, 0 when the invite isn't found and -1 when it is used up.
+var redeemInviteScript = redis.NewScript(`
+local invite = redis.call("HMGET", KEYS[1], "max_uses", "uses", "expires_at")
+if not invite[3] or tonumber(invite[3]) <= tonumber(ARGV[1]) then
+	return 0
+end
+local maxUses = tonumber(invite[1])
+if maxUses > 0 and tonumber(invite[2]) >= maxUses then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "uses", 1)
+`)
+
+// RedeemInvite counts a use of the invite, failing with storage.InviteExhausted
+// once it has been used up.
+func (s *Storage) RedeemInvite(roomUuid, id string) (*models.Invite, error) {
+	const op = "storage.redis.RedeemInvite"
+
+	ctx := context.Background()
+
+	invite, err := s.InviteById(roomUuid, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uses, err := redeemInviteScript.Run(ctx, s.cli, []string{inviteKey(roomUuid, id)}, time.Now().Unix()).Int()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case uses == 0:
+		return nil, fmt.Errorf("%s: %w", op, storage.InviteNotFound)
+	case uses < 0:
+		return nil, fmt.Errorf("%s: %w", op, storage.InviteExhausted)
+	}
+	invite.Uses = uses
+
+	return invite, nil
+}
+
+func (s *Storage) deleteInvites(ctx context.Context, roomUuid string) error {
+	ids, err := s.cli.SMembers(ctx, roomInvitesKey(roomUuid)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, inviteKey(roomUuid, id))
+	}
+	keys = append(keys, roomInvitesKey(roomUuid))
+
+	return s.cli.Del(ctx, keys...).Err()
+}
+
+func parseInvite(roomUuid, id string, data map[string]string) (*models.Invite, error) {
+	createdBy, err := strconv.ParseInt(data["created_by"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	maxUses, err := strconv.Atoi(data["max_uses"])
+	if err != nil {
+		return nil, err
+	}
+
+	uses, err := strconv.Atoi(data["uses"])
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := strconv.ParseInt(data["expires_at"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Invite{
+		Id:        id,
+		RoomUuid:  roomUuid,
+		CreatedBy: createdBy,
+		Username:  data["username"],
+		MaxUses:   maxUses,
+		Uses:      uses,
+		ExpiresAt: time.Unix(expiresAt, 0),
+	}, nil
+}
+
+func roomInvitesKey(uuid string) string {
+	return fmt.Sprintf("room:%s:invites", uuid)
+}
+
+func inviteKey(roomUuid, id string) string {
+	return fmt.Sprintf("room:%s:invite:%s", roomUuid, id)
+}
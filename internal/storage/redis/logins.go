@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginLockout returns how long the login attempts of the key are locked for.
+func (s *Storage) LoginLockout(key string) (time.Duration, error) {
+	const op = "storage.redis.LoginLockout"
+
+	ctx := context.Background()
+
+	ttl, err := s.cli.PTTL(ctx, loginLockKey(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// RecordLoginFailure counts a failed login attempt of the key, the count is forgotten
+// once no attempt has failed for ttl.
+func (s *Storage) RecordLoginFailure(key string, ttl time.Duration) (int64, error) {
+	const op = "storage.redis.RecordLoginFailure"
+
+	ctx := context.Background()
+
+	var incr *redis.IntCmd
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginFailuresKey(key))
+		pipe.PExpire(ctx, loginFailuresKey(key), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return incr.Val(), nil
+}
+
+// LockLogin locks the login attempts of the key for lockout and keeps its failures for ttl.
+func (s *Storage) LockLogin(key string, lockout, ttl time.Duration) error {
+	const op = "storage.redis.LockLogin"
+
+	ctx := context.Background()
+
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, loginLockKey(key), 1, lockout)
+		pipe.PExpire(ctx, loginFailuresKey(key), ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ResetLoginFailures(key string) error {
+	const op = "storage.redis.ResetLoginFailures"
+
+	ctx := context.Background()
+
+	if err := s.cli.Del(ctx, loginFailuresKey(key), loginLockKey(key)).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LoginLockouts returns the keys whose login attempts are currently locked.
+func (s *Storage) LoginLockouts() ([]*models.LoginLockout, error) {
+	const op = "storage.redis.LoginLockouts"
+
+	ctx := context.Background()
+
+	lockouts := make([]*models.LoginLockout, 0)
+
+	iter := s.cli.Scan(ctx, 0, loginLockKey("*"), 0).Iterator()
+	for iter.Next(ctx) {
+		key := strings.TrimPrefix(iter.Val(), loginLockKey(""))
+
+		ttl, err := s.cli.PTTL(ctx, loginLockKey(key)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if ttl < 0 {
+			continue
+		}
+
+		failures, err := s.cli.Get(ctx, loginFailuresKey(key)).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		kind, subject, _ := strings.Cut(key, ":")
+		lockouts = append(lockouts, &models.LoginLockout{
+			Kind:     kind,
+			Subject:  subject,
+			Failures: failures,
+			Until:    time.Now().Add(ttl),
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lockouts, nil
+}
+
+func loginFailuresKey(key string) string {
+	return fmt.Sprintf("login:failures:%s", key)
+}
+
+func loginLockKey(key string) string {
+	return fmt.Sprintf("login:lock:%s", key)
+}
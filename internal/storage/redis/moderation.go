@@ -0,0 +1,182 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// RoomRole returns the role stored for the user, users without one are plain members.
+// Owners aren't stored, they are known from the room itself.
+func (s *Storage) RoomRole(uuid string, userId int64) (models.Role, error) {
+	const op = "storage.redis.RoomRole"
+
+	ctx := context.Background()
+	role, err := s.cli.HGet(ctx, roomRolesKey(uuid), strconv.FormatInt(userId, 10)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return models.RoleMember, nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.Role(role), nil
+}
+
+func (s *Storage) SetRoomRole(uuid string, userId int64, role models.Role) error {
+	const op = "storage.redis.SetRoomRole"
+
+	ctx := context.Background()
+
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if role == models.RoleMember {
+			pipe.HDel(ctx, roomRolesKey(uuid), strconv.FormatInt(userId, 10))
+			pipe.ZRem(ctx, roomModeratorGrantsKey(uuid), userId)
+			return nil
+		}
+
+		pipe.HSet(ctx, roomRolesKey(uuid), strconv.FormatInt(userId, 10), string(role))
+		pipe.ZAddNX(ctx, roomModeratorGrantsKey(uuid), redis.Z{Score: float64(time.Now().Unix()), Member: userId})
+		pipe.SAdd(ctx, userRoomsKey(userId), uuid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RoomModerators returns the ids of the moderators of the room, the longest standing ones first.
+func (s *Storage) RoomModerators(uuid string) ([]int64, error) {
+	const op = "storage.redis.RoomModerators"
+
+	ctx := context.Background()
+	roles, err := s.cli.HGetAll(ctx, roomRolesKey(uuid)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, 0)
+	for member, role := range roles {
+		if models.Role(role) != models.RoleModerator {
+			continue
+		}
+
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := s.sortByGrantTime(ctx, roomModeratorGrantsKey(uuid), ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+func (s *Storage) BanUser(uuid string, userId int64) error {
+	const op = "storage.redis.BanUser"
+
+	ctx := context.Background()
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, roomBansKey(uuid), userId)
+		pipe.SAdd(ctx, userRoomsKey(userId), uuid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnbanUser(uuid string, userId int64) error {
+	const op = "storage.redis.UnbanUser"
+
+	ctx := context.Background()
+	if err := s.cli.SRem(ctx, roomBansKey(uuid), userId).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) IsBanned(uuid string, userId int64) (bool, error) {
+	const op = "storage.redis.IsBanned"
+
+	ctx := context.Background()
+	ok, err := s.cli.SIsMember(ctx, roomBansKey(uuid), userId).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ok, nil
+}
+
+func (s *Storage) MuteUser(uuid string, userId int64, until time.Time) error {
+	const op = "storage.redis.MuteUser"
+
+	ctx := context.Background()
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, roomMutesKey(uuid), strconv.FormatInt(userId, 10), until.Unix())
+		pipe.SAdd(ctx, userRoomsKey(userId), uuid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) UnmuteUser(uuid string, userId int64) error {
+	const op = "storage.redis.UnmuteUser"
+
+	ctx := context.Background()
+	if err := s.cli.HDel(ctx, roomMutesKey(uuid), strconv.FormatInt(userId, 10)).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MutedUntil returns when the mute of the user ends, the zero time if the user has never been muted.
+func (s *Storage) MutedUntil(uuid string, userId int64) (time.Time, error) {
+	const op = "storage.redis.MutedUntil"
+
+	ctx := context.Background()
+	until, err := s.cli.HGet(ctx, roomMutesKey(uuid), strconv.FormatInt(userId, 10)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return time.Unix(until, 0), nil
+}
+
+func roomRolesKey(uuid string) string {
+	return fmt.Sprintf("room:%s:roles", uuid)
+}
+
+// roomModeratorGrantsKey scores the moderators of the room with the time they became moderators.
+func roomModeratorGrantsKey(uuid string) string {
+	return fmt.Sprintf("room:%s:moderator_grants", uuid)
+}
+
+func roomBansKey(uuid string) string {
+	return fmt.Sprintf("room:%s:bans", uuid)
+}
+
+func roomMutesKey(uuid string) string {
+	return fmt.Sprintf("room:%s:mutes", uuid)
+}
@@ -0,0 +1,204 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// onlineElsewhereLua defines elsewhere, which reports whether the user is connected to the
+// room on another instance. Instances whose presence has expired are dropped on the way.
+const onlineElsewhereLua = `
+local function elsewhere(instances, self, prefix, user)
+	for _, instance in ipairs(redis.call("SMEMBERS", instances)) do
+		if instance ~= self then
+			local key = prefix .. instance
+			if redis.call("EXISTS", key) == 0 then
+				redis.call("SREM", instances, instance)
+			elseif redis.call("HEXISTS", key, user) == 1 then
+				return true
+			end
+		end
+	end
+	return false
+end
+`
+
+// addPresenceScript counts a connection of the user on this instance, it returns 1 when the
+// user has no other connection to the room on any instance.
+var addPresenceScript = redis.NewScript(onlineElsewhereLua + `
+local n = redis.call("HINCRBY", KEYS[2], ARGV[1], 1)
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+redis.call("SADD", KEYS[1], ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+if n > 1 or elsewhere(KEYS[1], ARGV[2], ARGV[4], ARGV[1]) then
+	return 0
+end
+return 1
+`)
+
+// removePresenceScript drops a connection of the user on this instance, it returns 1 when it
+// was the last connection of the user to the room on any instance.
+var removePresenceScript = redis.NewScript(onlineElsewhereLua + `
+local left = redis.call("HINCRBY", KEYS[2], ARGV[1], -1)
+if left > 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[2], ARGV[1])
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	redis.call("SREM", KEYS[1], ARGV[2])
+end
+if elsewhere(KEYS[1], ARGV[2], ARGV[4], ARGV[1]) then
+	return 0
+end
+return 1
+`)
+
+// AddPresence counts a connection of the user in the room, it reports whether it is the first one.
+func (s *Storage) AddPresence(roomUuid string, userId int64) (bool, error) {
+	const op = "storage.redis.AddPresence"
+
+	ctx := context.Background()
+
+	res, err := addPresenceScript.Run(ctx, s.cli, s.presenceKeys(roomUuid), s.presenceArgs(roomUuid, userId)...).Int()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res == 1, nil
+}
+
+// RemovePresence drops a connection of the user in the room, it reports whether it was the last one.
+func (s *Storage) RemovePresence(roomUuid string, userId int64) (bool, error) {
+	const op = "storage.redis.RemovePresence"
+
+	ctx := context.Background()
+
+	res, err := removePresenceScript.Run(ctx, s.cli, s.presenceKeys(roomUuid), s.presenceArgs(roomUuid, userId)...).Int()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res == 1, nil
+}
+
+// Heartbeat keeps alive the presence this instance holds in the rooms.
+func (s *Storage) Heartbeat(roomUuids []string) error {
+	const op = "storage.redis.Heartbeat"
+
+	ctx := context.Background()
+
+	_, err := s.cli.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, uuid := range roomUuids {
+			pipe.PExpire(ctx, roomInstancePresenceKey(uuid, s.instance), s.presenceExpire)
+			pipe.SAdd(ctx, roomPresenceKey(uuid), s.instance)
+			pipe.PExpire(ctx, roomPresenceKey(uuid), s.presenceExpire)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// OnlineUsers returns the ids of the users connected to the room on any instance.
+func (s *Storage) OnlineUsers(roomUuid string) ([]int64, error) {
+	const op = "storage.redis.OnlineUsers"
+
+	online, err := s.online(context.Background(), []string{roomUuid})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, 0, len(online[roomUuid]))
+	for id := range online[roomUuid] {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// OnlineCounts returns the number of users connected to each of the rooms.
+func (s *Storage) OnlineCounts(roomUuids []string) (map[string]int, error) {
+	const op = "storage.redis.OnlineCounts"
+
+	online, err := s.online(context.Background(), roomUuids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	counts := make(map[string]int, len(roomUuids))
+	for _, uuid := range roomUuids {
+		counts[uuid] = len(online[uuid])
+	}
+
+	return counts, nil
+}
+
+// online collects the users connected to each of the rooms across the instances holding
+// a presence in it.
+func (s *Storage) online(ctx context.Context, roomUuids []string) (map[string]map[int64]struct{}, error) {
+	instanceCmds := make([]*redis.StringSliceCmd, len(roomUuids))
+	_, err := s.cli.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, uuid := range roomUuids {
+			instanceCmds[i] = pipe.SMembers(ctx, roomPresenceKey(uuid))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userCmds := make([][]*redis.StringSliceCmd, len(roomUuids))
+	_, err = s.cli.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, uuid := range roomUuids {
+			for _, instance := range instanceCmds[i].Val() {
+				userCmds[i] = append(userCmds[i], pipe.HKeys(ctx, roomInstancePresenceKey(uuid, instance)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[string]map[int64]struct{}, len(roomUuids))
+	for i, uuid := range roomUuids {
+		users := make(map[int64]struct{})
+		for _, cmd := range userCmds[i] {
+			for _, field := range cmd.Val() {
+				id, err := strconv.ParseInt(field, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				users[id] = struct{}{}
+			}
+		}
+		online[uuid] = users
+	}
+
+	return online, nil
+}
+
+func (s *Storage) presenceKeys(roomUuid string) []string {
+	return []string{roomPresenceKey(roomUuid), roomInstancePresenceKey(roomUuid, s.instance)}
+}
+
+func (s *Storage) presenceArgs(roomUuid string, userId int64) []interface{} {
+	return []interface{}{userId, s.instance, s.presenceExpire.Milliseconds(), roomInstancePresenceKey(roomUuid, "")}
+}
+
+// roomPresenceKey holds the ids of the instances the users of the room are connected to.
+func roomPresenceKey(uuid string) string {
+	return fmt.Sprintf("room:%s:presence", uuid)
+}
+
+// roomInstancePresenceKey maps the ids of the users connected to the room through the instance
+// to their number of connections, it expires unless the instance keeps sending heartbeats.
+func roomInstancePresenceKey(uuid, instance string) string {
+	return fmt.Sprintf("room:%s:presence:%s", uuid, instance)
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+)
+
+func (s *Storage) Publish(channel string, payload []byte) error {
+	const op = "storage.redis.Publish"
+
+	ctx := context.Background()
+	if err := s.cli.Publish(ctx, channel, payload).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Subscribe calls the handler with every payload published to a channel matching the
+// pattern until unsubscribed, all the matching channels share a single connection.
+func (s *Storage) Subscribe(pattern string, handler func(channel string, payload []byte)) (func(), error) {
+	const op = "storage.redis.Subscribe"
+
+	ctx := context.Background()
+
+	pubsub := s.cli.PSubscribe(ctx, pattern)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			handler(msg.Channel, []byte(msg.Payload))
+		}
+	}()
+
+	return func() { pubsub.Close() }, nil
+}
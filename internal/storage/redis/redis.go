@@ -1,10 +1,14 @@
 package redis
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/guluzadehh/go_chat/internal/config"
@@ -15,6 +19,10 @@ import (
 
 type Storage struct {
 	cli *redis.Client
+
+	// instance tells apart the presence kept by each running instance.
+	instance       string
+	presenceExpire time.Duration
 }
 
 func New(config *config.Config) (*Storage, error) {
@@ -29,7 +37,9 @@ func New(config *config.Config) (*Storage, error) {
 	}
 
 	return &Storage{
-		cli: cli,
+		cli:            cli,
+		instance:       uuid.NewString(),
+		presenceExpire: config.Chat.Presence.Expire,
 	}, nil
 }
 
@@ -50,12 +60,16 @@ func (s *Storage) CreateRoom(name, password string, owner_id int64) (*models.Roo
 		OwnerId:  owner_id,
 	}
 
-	hashKey := fmt.Sprintf("room:%s", room.Uuid)
-	err = s.cli.HSet(ctx, hashKey, map[string]interface{}{
-		"name":     room.Name,
-		"password": room.Password,
-		"owner_id": room.OwnerId,
-	}).Err()
+	hashKey := roomKey(room.Uuid)
+	_, err = s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, hashKey, map[string]interface{}{
+			"name":     room.Name,
+			"password": room.Password,
+			"owner_id": room.OwnerId,
+		})
+		pipe.SAdd(ctx, userRoomsKey(room.OwnerId), room.Uuid)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -75,23 +89,20 @@ func (s *Storage) Rooms() ([]*models.Room, error) {
 
 	var rooms []*models.Room
 	for _, key := range keys {
+		if !isRoomKey(key) {
+			continue
+		}
+
 		roomData, err := s.cli.HGetAll(ctx, key).Result()
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		owner_id, err := strconv.ParseInt(roomData["owner_id"], 10, 64)
+		room, err := parseRoom(parseRoomUuid(key), roomData)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		room := &models.Room{
-			Uuid:     parseRoomUuid(key),
-			Name:     roomData["name"],
-			Password: roomData["password"],
-			OwnerId:  owner_id,
-		}
-
 		rooms = append(rooms, room)
 	}
 
@@ -103,41 +114,307 @@ func (s *Storage) RoomByUuid(uuid string) (*models.Room, error) {
 
 	ctx := context.Background()
 
-	roomData, err := s.cli.HGetAll(ctx, fmt.Sprintf("room:%s", uuid)).Result()
+	roomData, err := s.cli.HGetAll(ctx, roomKey(uuid)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, storage.RoomNotFound
 		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	if len(roomData) == 0 {
+		return nil, storage.RoomNotFound
+	}
 
-	owner_id, err := strconv.ParseInt(roomData["owner_id"], 10, 64)
+	room, err := parseRoom(uuid, roomData)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &models.Room{
-		Uuid:     roomData["uuid"],
-		Name:     roomData["name"],
-		Password: roomData["password"],
-		OwnerId:  owner_id,
-	}, nil
+	return room, nil
+}
+
+func (s *Storage) SetRoomPassword(uuid, password string) error {
+	const op = "storage.redis.SetRoomPassword"
+
+	ctx := context.Background()
+	if err := s.cli.HSet(ctx, roomKey(uuid), "password", password).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
 func (s *Storage) DeleteRoom(uuid string) error {
 	const op = "storage.redis.DeleteRoom"
 
 	ctx := context.Background()
-	res, err := s.cli.Del(ctx, fmt.Sprintf("room:%s", uuid)).Result()
+	res, err := s.cli.Del(ctx, roomKey(uuid)).Result()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	if res == 0 {
 		return storage.RoomNotFound
 	}
+
+	if err := s.deleteInvites(ctx, uuid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.cli.Del(ctx, roomSubKeys(uuid)...).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) AddRoomMember(uuid string, userId int64) error {
+	const op = "storage.redis.AddRoomMember"
+
+	ctx := context.Background()
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, roomMembersKey(uuid), userId)
+		pipe.ZAddNX(ctx, roomMemberGrantsKey(uuid), redis.Z{Score: float64(time.Now().Unix()), Member: userId})
+		pipe.SAdd(ctx, userRoomsKey(userId), uuid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveRoomMember(uuid string, userId int64) error {
+	const op = "storage.redis.RemoveRoomMember"
+
+	ctx := context.Background()
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, roomMembersKey(uuid), userId)
+		pipe.ZRem(ctx, roomMemberGrantsKey(uuid), userId)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
+func (s *Storage) IsRoomMember(uuid string, userId int64) (bool, error) {
+	const op = "storage.redis.IsRoomMember"
+
+	ctx := context.Background()
+	ok, err := s.cli.SIsMember(ctx, roomMembersKey(uuid), userId).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ok, nil
+}
+
+// RoomMembers returns the ids of the users who have been granted access to the room,
+// the longest standing members first.
+func (s *Storage) RoomMembers(uuid string) ([]int64, error) {
+	const op = "storage.redis.RoomMembers"
+
+	ctx := context.Background()
+	members, err := s.cli.SMembers(ctx, roomMembersKey(uuid)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := s.sortByGrantTime(ctx, roomMemberGrantsKey(uuid), ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// TransferRoom makes the user the owner of the room, the previous owner loses its access.
+func (s *Storage) TransferRoom(uuid string, ownerId int64) error {
+	const op = "storage.redis.TransferRoom"
+
+	ctx := context.Background()
+
+	room, err := s.RoomByUuid(uuid)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, roomKey(uuid), "owner_id", ownerId)
+		pipe.HDel(ctx, roomRolesKey(uuid), strconv.FormatInt(ownerId, 10))
+		pipe.ZRem(ctx, roomModeratorGrantsKey(uuid), ownerId)
+		pipe.SAdd(ctx, roomMembersKey(uuid), ownerId)
+		pipe.ZAddNX(ctx, roomMemberGrantsKey(uuid), redis.Z{Score: float64(time.Now().Unix()), Member: ownerId})
+		pipe.SAdd(ctx, userRoomsKey(ownerId), uuid)
+		pipe.SRem(ctx, roomMembersKey(uuid), room.OwnerId)
+		pipe.ZRem(ctx, roomMemberGrantsKey(uuid), room.OwnerId)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserRooms returns the rooms the user owns, takes part in, or holds a membership, role, ban or
+// mute in. Rooms deleted since are dropped from the index of the user.
+func (s *Storage) UserRooms(userId int64) ([]*models.Room, error) {
+	const op = "storage.redis.UserRooms"
+
+	ctx := context.Background()
+
+	uuids, err := s.cli.SMembers(ctx, userRoomsKey(userId)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rooms := make([]*models.Room, 0, len(uuids))
+	for _, uuid := range uuids {
+		room, err := s.RoomByUuid(uuid)
+		if errors.Is(err, storage.RoomNotFound) {
+			if err := s.cli.SRem(ctx, userRoomsKey(userId), uuid).Err(); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		rooms = append(rooms, room)
+	}
+
+	return rooms, nil
+}
+
+// PurgeRoomUser removes the membership, role, ban and mute of the user in the room, and the
+// room from the index of the user.
+func (s *Storage) PurgeRoomUser(uuid string, userId int64) error {
+	const op = "storage.redis.PurgeRoomUser"
+
+	ctx := context.Background()
+	field := strconv.FormatInt(userId, 10)
+
+	_, err := s.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, roomMembersKey(uuid), userId)
+		pipe.ZRem(ctx, roomMemberGrantsKey(uuid), userId)
+		pipe.HDel(ctx, roomRolesKey(uuid), field)
+		pipe.ZRem(ctx, roomModeratorGrantsKey(uuid), userId)
+		pipe.SRem(ctx, roomBansKey(uuid), userId)
+		pipe.HDel(ctx, roomMutesKey(uuid), field)
+		pipe.SRem(ctx, userRoomsKey(userId), uuid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sortByGrantTime orders the ids by the time recorded for them in the sorted set, the ones
+// granted before the times were recorded come first, the lowest ids first.
+func (s *Storage) sortByGrantTime(ctx context.Context, key string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+
+	scores, err := s.cli.ZMScore(ctx, key, members...).Result()
+	if err != nil {
+		return err
+	}
+
+	granted := make(map[int64]float64, len(ids))
+	for i, id := range ids {
+		granted[id] = scores[i]
+	}
+
+	slices.SortFunc(ids, func(a, b int64) int {
+		return cmp.Or(cmp.Compare(granted[a], granted[b]), cmp.Compare(a, b))
+	})
+
+	return nil
+}
+
+// parseRoom builds the room from the fields of its hash.
+func parseRoom(uuid string, roomData map[string]string) (*models.Room, error) {
+	owner_id, err := strconv.ParseInt(roomData["owner_id"], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var peer_id int64
+	if roomData["peer_id"] != "" {
+		peer_id, err = strconv.ParseInt(roomData["peer_id"], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Room{
+		Uuid:     uuid,
+		Name:     roomData["name"],
+		Password: roomData["password"],
+		OwnerId:  owner_id,
+		PeerId:   peer_id,
+	}, nil
+}
+
+func roomKey(uuid string) string {
+	return fmt.Sprintf("room:%s", uuid)
+}
+
+// roomMembersKey holds ids of users who have been granted access to a private room.
+func roomMembersKey(uuid string) string {
+	return fmt.Sprintf("room:%s:members", uuid)
+}
+
+// roomMemberGrantsKey scores the members of the room with the time they were granted access.
+func roomMemberGrantsKey(uuid string) string {
+	return fmt.Sprintf("room:%s:member_grants", uuid)
+}
+
+// userRoomsKey holds the uuids of the rooms the user owns, takes part in, or holds a
+// membership, role, ban or mute in.
+func userRoomsKey(userId int64) string {
+	return fmt.Sprintf("user:%d:rooms", userId)
+}
+
+// roomSubKeys lists the keys kept next to the room hash, they are deleted along with the room.
+func roomSubKeys(uuid string) []string {
+	return []string{
+		roomMembersKey(uuid),
+		roomMemberGrantsKey(uuid),
+		roomRolesKey(uuid),
+		roomModeratorGrantsKey(uuid),
+		roomBansKey(uuid),
+		roomMutesKey(uuid),
+		roomPresenceKey(uuid),
+	}
+}
+
+// isRoomKey reports whether the key is a room hash and not one of its sub-keys.
+func isRoomKey(key string) bool {
+	return strings.Count(key, ":") == 1
+}
+
 func parseRoomUuid(key string) string {
 	var uuid string
 	fmt.Sscanf(key, "room:%s", &uuid)
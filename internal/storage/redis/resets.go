@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// SavePasswordReset stores the hash of a password reset token of the user for ttl.
+func (s *Storage) SavePasswordReset(hash string, userId int64, ttl time.Duration) error {
+	const op = "storage.redis.SavePasswordReset"
+
+	ctx := context.Background()
+
+	if err := s.cli.Set(ctx, passwordResetKey(hash), userId, ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TakePasswordReset returns the user of the password reset token and forgets the token.
+func (s *Storage) TakePasswordReset(hash string) (int64, error) {
+	const op = "storage.redis.TakePasswordReset"
+
+	ctx := context.Background()
+
+	userId, err := s.cli.GetDel(ctx, passwordResetKey(hash)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ResetTokenNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userId, nil
+}
+
+func passwordResetKey(hash string) string {
+	return fmt.Sprintf("password_reset:%s", hash)
+}
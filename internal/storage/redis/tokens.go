@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// rotateRefreshScript swaps the current token of the family for the new one, the family
+// keeps the expiry it was started with. A token which isn't the current one has already
+// been rotated, so the family is revoked.
+var rotateRefreshScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return -1
+end
+redis.call("SET", KEYS[1], ARGV[2], "KEEPTTL")
+return 1
+`)
+
+// SaveRefreshToken starts the family of refresh tokens with its first token.
+func (s *Storage) SaveRefreshToken(family, jti string, ttl time.Duration) error {
+	const op = "storage.redis.SaveRefreshToken"
+
+	ctx := context.Background()
+
+	if err := s.cli.Set(ctx, refreshFamilyKey(family), jti, ttl).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken replaces the token jti of the family with newJti. It fails with
+// storage.RefreshTokenReused when jti has already been rotated, the family is revoked then.
+// Rotation doesn't extend the family, it expires when the session it was started by does.
+func (s *Storage) RotateRefreshToken(family, jti, newJti string) error {
+	const op = "storage.redis.RotateRefreshToken"
+
+	ctx := context.Background()
+
+	res, err := rotateRefreshScript.Run(ctx, s.cli, []string{refreshFamilyKey(family)}, jti, newJti).Int()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch res {
+	case 0:
+		return fmt.Errorf("%s: %w", op, storage.RefreshTokenNotFound)
+	case -1:
+		return fmt.Errorf("%s: %w", op, storage.RefreshTokenReused)
+	}
+
+	return nil
+}
+
+func (s *Storage) RevokeRefreshFamily(family string) error {
+	const op = "storage.redis.RevokeRefreshFamily"
+
+	ctx := context.Background()
+
+	if err := s.cli.Del(ctx, refreshFamilyKey(family)).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TokenVersion returns the version the tokens of the user must carry to be accepted.
+func (s *Storage) TokenVersion(userId int64) (int64, error) {
+	const op = "storage.redis.TokenVersion"
+
+	ctx := context.Background()
+
+	version, err := s.cli.Get(ctx, tokenVersionKey(userId)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// RevokeUserTokens bumps the token version of the user, invalidating all of its tokens.
+func (s *Storage) RevokeUserTokens(userId int64) (int64, error) {
+	const op = "storage.redis.RevokeUserTokens"
+
+	ctx := context.Background()
+
+	version, err := s.cli.Incr(ctx, tokenVersionKey(userId)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+func tokenVersionKey(userId int64) string {
+	return fmt.Sprintf("user:%d:token_version", userId)
+}
+
+func refreshFamilyKey(family string) string {
+	return fmt.Sprintf("refresh:%s", family)
+}
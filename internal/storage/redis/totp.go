@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// totpStepTTL outlives the time steps a code is accepted in.
+const totpStepTTL = 2 * time.Minute
+
+// UseTotpStep remembers that the user has authenticated with the code of the time step,
+// it reports false when the step has already been used.
+func (s *Storage) UseTotpStep(userId, step int64) (bool, error) {
+	const op = "storage.redis.UseTotpStep"
+
+	ctx := context.Background()
+
+	ok, err := s.cli.SetNX(ctx, totpStepKey(userId, step), 1, totpStepTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ok, nil
+}
+
+func totpStepKey(userId, step int64) string {
+	return fmt.Sprintf("user:%d:totp:%d", userId, step)
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/db"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// CreateMentions records that the message of the room mentions the users, mentions that
+// already exist are kept as they are.
+func (s *Storage) CreateMentions(messageId int64, roomUuid string, userIds []int64, createdAt time.Time) error {
+	const op = "storage.sqlite.CreateMentions"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	const query = `INSERT OR IGNORE INTO mentions("message_id", "user_id", "room_uuid", "created_at") VALUES(?, ?, ?, ?)`
+	for _, userId := range userIds {
+		if _, err := tx.Exec(query, messageId, userId, roomUuid, createdAt); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Mentions returns up to limit mentions of the user in messages with an id lower than
+// before, latest first. A non-positive before starts from the latest mention, mentions in
+// deleted messages are left out.
+func (s *Storage) Mentions(userId, before int64, limit int, unreadOnly bool) ([]*models.Mention, error) {
+	const op = "storage.sqlite.Mentions"
+
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+
+	query := `
+		SELECT n.message_id, n.read_at FROM mentions n
+		JOIN messages m ON m.id = n.message_id
+		WHERE n.user_id = ? AND n.message_id < ? AND m.deleted_at IS NULL
+	`
+	if unreadOnly {
+		query += ` AND n.read_at IS NULL`
+	}
+	query += ` ORDER BY n.message_id DESC LIMIT ?`
+
+	rows, err := s.db.Query(query, userId, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	mentions := make([]*models.Mention, 0)
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var messageId int64
+		var readAt sql.NullTime
+		if err := rows.Scan(&messageId, &readAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		mentions = append(mentions, &models.Mention{
+			UserId:  userId,
+			Message: &models.Message{Id: messageId},
+			ReadAt:  readAt.Time,
+		})
+		ids = append(ids, messageId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	messages, err := s.messagesWithIds(ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, mention := range mentions {
+		if msg, ok := messages[mention.Message.Id]; ok {
+			mention.Message = msg
+		}
+	}
+
+	return mentions, nil
+}
+
+// UnreadMentionCount returns the number of mentions the user hasn't read yet.
+func (s *Storage) UnreadMentionCount(userId int64) (int, error) {
+	const op = "storage.sqlite.UnreadMentionCount"
+
+	const query = `
+		SELECT COUNT(*) FROM mentions n
+		JOIN messages m ON m.id = n.message_id
+		WHERE n.user_id = ? AND n.read_at IS NULL AND m.deleted_at IS NULL
+	`
+
+	var count int
+	if err := s.db.QueryRow(query, userId).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// MarkMentionsRead marks the mentions of the user in the messages as read, every mention
+// of the user is read when no message is given.
+func (s *Storage) MarkMentionsRead(userId int64, messageIds []int64) error {
+	const op = "storage.sqlite.MarkMentionsRead"
+
+	query := `UPDATE mentions SET read_at = ? WHERE user_id = ? AND read_at IS NULL`
+	args := []interface{}{time.Now().UTC(), userId}
+
+	if len(messageIds) > 0 {
+		query += fmt.Sprintf(` AND message_id IN (%s)`, db.Placeholders(len(messageIds)))
+		for _, id := range messageIds {
+			args = append(args, id)
+		}
+	}
+
+	if _, err := s.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) messagesWithIds(ids []int64) (map[int64]*models.Message, error) {
+	messages := make(map[int64]*models.Message, len(ids))
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM messages WHERE id IN (%s)`, messageColumns, db.Placeholders(len(ids)))
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range found {
+		messages[msg.Id] = msg
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,268 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/db"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+	"github.com/mattn/go-sqlite3"
+)
+
+const messageColumns = `id, room_uuid, user_id, message, COALESCE(client_id, ''), created_at, COALESCE(reply_to, 0), COALESCE(thread_root, 0), edited_at, deleted_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row scanner) (*models.Message, error) {
+	var msg models.Message
+	var editedAt, deletedAt sql.NullTime
+	err := row.Scan(&msg.Id, &msg.RoomUuid, &msg.UserId, &msg.Text, &msg.ClientId, &msg.CreatedAt, &msg.ReplyTo, &msg.ThreadRoot, &editedAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.EditedAt = editedAt.Time
+	msg.DeletedAt = deletedAt.Time
+
+	return &msg, nil
+}
+
+func scanMessages(rows *sql.Rows) ([]*models.Message, error) {
+	defer rows.Close()
+
+	messages := make([]*models.Message, 0)
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// CreateMessage saves a message of the room, replyTo and threadRoot are zero unless the
+// message is a reply.
+func (s *Storage) CreateMessage(roomUuid string, userId int64, text, clientId string, replyTo, threadRoot int64, createdAt time.Time) (*models.Message, error) {
+	const op = "storage.sqlite.CreateMessage"
+
+	const query = `
+		INSERT INTO messages("room_uuid", "user_id", "message", "client_id", "reply_to", "thread_root", "created_at")
+		VALUES(?, ?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, 0), ?)
+	`
+	res, err := s.db.Exec(query, roomUuid, userId, text, clientId, replyTo, threadRoot, createdAt)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return nil, fmt.Errorf("%s: %w", op, storage.MessageExists)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lastInsertedId, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.Message{
+		Id:         lastInsertedId,
+		RoomUuid:   roomUuid,
+		UserId:     userId,
+		Text:       text,
+		ClientId:   clientId,
+		CreatedAt:  createdAt,
+		ReplyTo:    replyTo,
+		ThreadRoot: threadRoot,
+	}, nil
+}
+
+// MessageByClientId returns the message the user has sent to the room with the client id.
+func (s *Storage) MessageByClientId(roomUuid string, userId int64, clientId string) (*models.Message, error) {
+	const op = "storage.sqlite.MessageByClientId"
+
+	query := fmt.Sprintf(`SELECT %s FROM messages WHERE user_id = ? AND room_uuid = ? AND client_id = ?`, messageColumns)
+	msg, err := scanMessage(s.db.QueryRow(query, userId, roomUuid, clientId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, storage.MessageNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// ClearClientId frees the client id of the message, so that the user can send another
+// message with it.
+func (s *Storage) ClearClientId(id int64) error {
+	const op = "storage.sqlite.ClearClientId"
+
+	if _, err := s.db.Exec(`UPDATE messages SET client_id = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MessageById returns the message, deleted ones included.
+func (s *Storage) MessageById(id int64) (*models.Message, error) {
+	const op = "storage.sqlite.MessageById"
+
+	query := fmt.Sprintf(`SELECT %s FROM messages WHERE id = ?`, messageColumns)
+	msg, err := scanMessage(s.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, storage.MessageNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msg, nil
+}
+
+// EditMessage replaces the text of a message that hasn't been deleted.
+func (s *Storage) EditMessage(id int64, text string, editedAt time.Time) error {
+	const op = "storage.sqlite.EditMessage"
+
+	const query = `UPDATE messages SET message = ?, edited_at = ? WHERE id = ? AND deleted_at IS NULL`
+	if err := s.execMessageUpdate(query, text, editedAt, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteMessage clears the text of the message and marks it as deleted, the row is kept
+// so that cursors pointing at it stay valid.
+func (s *Storage) DeleteMessage(id int64, deletedAt time.Time) error {
+	const op = "storage.sqlite.DeleteMessage"
+
+	const query = `UPDATE messages SET message = '', deleted_at = ? WHERE id = ? AND deleted_at IS NULL`
+	if err := s.execMessageUpdate(query, deletedAt, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// execMessageUpdate runs an update of a single message, storage.MessageNotFound is
+// returned when no message has been updated.
+func (s *Storage) execMessageUpdate(query string, args ...interface{}) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.MessageNotFound
+	}
+
+	return nil
+}
+
+// Messages returns up to limit messages of the room with an id lower than before,
+// oldest first. A non-positive before starts from the latest message.
+func (s *Storage) Messages(roomUuid string, before int64, limit int) ([]*models.Message, error) {
+	const op = "storage.sqlite.Messages"
+
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM messages
+		WHERE room_uuid = ? AND id < ? AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT ?
+	`, messageColumns)
+	rows, err := s.db.Query(query, roomUuid, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slices.Reverse(messages)
+
+	return messages, nil
+}
+
+// MessagesAfter returns up to limit messages of the room with an id greater than after,
+// the ones right after it first.
+func (s *Storage) MessagesAfter(roomUuid string, after int64, limit int) ([]*models.Message, error) {
+	const op = "storage.sqlite.MessagesAfter"
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM messages
+		WHERE room_uuid = ? AND id > ? AND deleted_at IS NULL
+		ORDER BY id ASC
+		LIMIT ?
+	`, messageColumns)
+	rows, err := s.db.Query(query, roomUuid, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return messages, nil
+}
+
+// LastMessages returns the latest message of each of the rooms, rooms without messages
+// are left out.
+func (s *Storage) LastMessages(roomUuids []string) (map[string]*models.Message, error) {
+	const op = "storage.sqlite.LastMessages"
+
+	messages := make(map[string]*models.Message)
+	if len(roomUuids) == 0 {
+		return messages, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM messages
+		WHERE id IN (SELECT MAX(id) FROM messages WHERE room_uuid IN (%s) AND deleted_at IS NULL GROUP BY room_uuid)
+	`, messageColumns, db.Placeholders(len(roomUuids)))
+
+	args := make([]interface{}, 0, len(roomUuids))
+	for _, uuid := range roomUuids {
+		args = append(args, uuid)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	last, err := scanMessages(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, msg := range last {
+		messages[msg.RoomUuid] = msg
+	}
+
+	return messages, nil
+}
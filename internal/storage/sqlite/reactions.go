@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/db"
+	"github.com/guluzadehh/go_chat/internal/models"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// AddReaction puts the emoji of the user on the message, it reports whether the reaction
+// is new. A message holds at most maxEmoji different emoji.
+func (s *Storage) AddReaction(messageId, userId int64, emoji string, maxEmoji int) (bool, error) {
+	const op = "storage.sqlite.AddReaction"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var others int
+	err = tx.QueryRow(`SELECT COUNT(DISTINCT emoji) FROM message_reactions WHERE message_id = ? AND emoji != ?`, messageId, emoji).Scan(&others)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM message_reactions WHERE message_id = ? AND emoji = ?)`, messageId, emoji).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !exists && others >= maxEmoji {
+		return false, fmt.Errorf("%s: %w", op, storage.ReactionLimitReached)
+	}
+
+	const query = `INSERT OR IGNORE INTO message_reactions("message_id", "user_id", "emoji", "created_at") VALUES(?, ?, ?, ?)`
+	res, err := tx.Exec(query, messageId, userId, emoji, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// RemoveReaction takes the emoji of the user off the message, it reports whether there was one.
+func (s *Storage) RemoveReaction(messageId, userId int64, emoji string) (bool, error) {
+	const op = "storage.sqlite.RemoveReaction"
+
+	res, err := s.db.Exec(`DELETE FROM message_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?`, messageId, userId, emoji)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// Reactions returns the reactions of each of the messages, the emoji in the order they
+// were first put on the message.
+func (s *Storage) Reactions(messageIds []int64) (map[int64][]*models.Reaction, error) {
+	const op = "storage.sqlite.Reactions"
+
+	reactions := make(map[int64][]*models.Reaction)
+	if len(messageIds) == 0 {
+		return reactions, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT message_id, emoji, user_id FROM message_reactions
+		WHERE message_id IN (%s)
+		ORDER BY created_at, user_id
+	`, db.Placeholders(len(messageIds)))
+
+	args := make([]interface{}, 0, len(messageIds))
+	for _, id := range messageIds {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageId, userId int64
+		var emoji string
+		if err := rows.Scan(&messageId, &emoji, &userId); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		reactions[messageId] = addReaction(reactions[messageId], emoji, userId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reactions, nil
+}
+
+func addReaction(reactions []*models.Reaction, emoji string, userId int64) []*models.Reaction {
+	for _, reaction := range reactions {
+		if reaction.Emoji == emoji {
+			reaction.UserIds = append(reaction.UserIds, userId)
+			return reactions
+		}
+	}
+
+	return append(reactions, &models.Reaction{Emoji: emoji, UserIds: []int64{userId}})
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/db"
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// MarkRead moves the read cursor of the user in the room up to the message, it reports
+// whether the cursor has moved. A cursor is never moved back. The mentions of the user
+// in the room up to the message are read along with it.
+func (s *Storage) MarkRead(userId int64, roomUuid string, messageId int64) (bool, error) {
+	const op = "storage.sqlite.MarkRead"
+
+	var id int64
+	err := s.db.QueryRow(`SELECT id FROM messages WHERE id = ? AND room_uuid = ?`, messageId, roomUuid).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, fmt.Errorf("%s: %w", op, storage.MessageNotFound)
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	const query = `
+		INSERT INTO room_reads("user_id", "room_uuid", "message_id", "updated_at") VALUES(?, ?, ?, ?)
+		ON CONFLICT(user_id, room_uuid) DO UPDATE SET message_id = excluded.message_id, updated_at = excluded.updated_at
+		WHERE excluded.message_id > room_reads.message_id
+	`
+	res, err := s.db.Exec(query, userId, roomUuid, messageId, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected > 0 {
+		const mentionsQuery = `UPDATE mentions SET read_at = ? WHERE user_id = ? AND room_uuid = ? AND message_id <= ? AND read_at IS NULL`
+		if _, err := s.db.Exec(mentionsQuery, time.Now().UTC(), userId, roomUuid, messageId); err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return affected > 0, nil
+}
+
+// UnreadCounts returns the number of messages posted by others after the read cursor of
+// the user in each of the rooms.
+func (s *Storage) UnreadCounts(userId int64, roomUuids []string) (map[string]int, error) {
+	const op = "storage.sqlite.UnreadCounts"
+
+	counts := make(map[string]int, len(roomUuids))
+	if len(roomUuids) == 0 {
+		return counts, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT m.room_uuid, COUNT(*) FROM messages m
+		LEFT JOIN room_reads r ON r.room_uuid = m.room_uuid AND r.user_id = ?
+		WHERE m.room_uuid IN (%s) AND m.id > COALESCE(r.message_id, 0) AND m.user_id != ? AND m.deleted_at IS NULL
+		GROUP BY m.room_uuid
+	`, db.Placeholders(len(roomUuids)))
+
+	args := make([]interface{}, 0, len(roomUuids)+2)
+	args = append(args, userId)
+	for _, uuid := range roomUuids {
+		args = append(args, uuid)
+	}
+	args = append(args, userId)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uuid string
+		var count int
+		if err := rows.Scan(&uuid, &count); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		counts[uuid] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return counts, nil
+}
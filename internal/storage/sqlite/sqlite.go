@@ -3,6 +3,8 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/guluzadehh/go_chat/internal/lib/db"
 	"github.com/guluzadehh/go_chat/internal/models"
@@ -10,6 +12,9 @@ import (
 	"github.com/mattn/go-sqlite3"
 )
 
+const userColumns = "id, username, password, COALESCE(email, ''), is_admin, COALESCE(totp_secret, ''), totp_enabled, " +
+	"COALESCE(display_name, ''), COALESCE(avatar_url, ''), COALESCE(bio, ''), created_at"
+
 type Storage struct {
 	db *sql.DB
 }
@@ -28,10 +33,25 @@ func New(storagePath string) (*Storage, error) {
 func (s *Storage) UserByUsername(username string) (*models.User, error) {
 	const op = "storage.sqlite.UserByUsername"
 
-	var user models.User
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE username = ?`, userColumns)
+	user, err := scanUser(s.db.QueryRow(query, username))
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s: %w", op, storage.UserNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
 
-	const query = `SELECT * FROM users WHERE username = ?`
-	err := s.db.QueryRow(query, username).Scan(&user.Id, &user.Username, &user.Password)
+func (s *Storage) UserById(id int64) (*models.User, error) {
+	const op = "storage.sqlite.UserById"
+
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE id = ?`, userColumns)
+	user, err := scanUser(s.db.QueryRow(query, id))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -41,21 +61,26 @@ func (s *Storage) UserByUsername(username string) (*models.User, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
-func (s *Storage) CreateUser(username, password string) (*models.User, error) {
+func (s *Storage) CreateUser(username, password, email string) (*models.User, error) {
 	const op = "storage.sqlite.CreateUser"
 
-	const query = `INSERT INTO users("username", "password") VALUES(?, ?)`
+	const query = `INSERT INTO users("username", "password", "email", "created_at") VALUES(?, ?, NULLIF(?, ''), ?)`
 	stmt, err := s.db.Prepare(query)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	res, err := stmt.Exec(username, password)
+	createdAt := time.Now().UTC()
+
+	res, err := stmt.Exec(username, password, email, createdAt)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			if strings.Contains(sqliteErr.Error(), "users.email") {
+				return nil, fmt.Errorf("%s: %w", op, storage.EmailExists)
+			}
 			return nil, fmt.Errorf("%s: %w", op, storage.UsernameExists)
 		}
 
@@ -67,13 +92,13 @@ func (s *Storage) CreateUser(username, password string) (*models.User, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &models.User{Id: lastInsertedId, Username: username, Password: password}, nil
+	return &models.User{Id: lastInsertedId, Username: username, Password: password, Email: email, CreatedAt: createdAt}, nil
 }
 
 func (s *Storage) UsersWithIds(ids []int64) (map[int64]*models.User, error) {
 	const op = "storage.sqlite.UsersWithIds"
 
-	query := fmt.Sprintf(`SELECT * FROM users WHERE users.id IN (%s)`, db.Placeholders(len(ids)))
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE users.id IN (%s)`, userColumns, db.Placeholders(len(ids)))
 
 	args := make([]interface{}, 0)
 	for _, id := range ids {
@@ -87,12 +112,153 @@ func (s *Storage) UsersWithIds(ids []int64) (map[int64]*models.User, error) {
 
 	users := make(map[int64]*models.User)
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.Id, &user.Username, &user.Password); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
-		users[user.Id] = &user
+		users[user.Id] = user
 	}
 
 	return users, nil
 }
+
+// UsersWithUsernames returns the users with the given usernames, unknown ones are left out.
+func (s *Storage) UsersWithUsernames(usernames []string) ([]*models.User, error) {
+	const op = "storage.sqlite.UsersWithUsernames"
+
+	users := make([]*models.User, 0)
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE username IN (%s)`, userColumns, db.Placeholders(len(usernames)))
+
+	args := make([]interface{}, 0, len(usernames))
+	for _, username := range usernames {
+		args = append(args, username)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) UpdatePassword(userId int64, password string) error {
+	const op = "storage.sqlite.UpdatePassword"
+
+	const query = `UPDATE users SET password = ? WHERE id = ?`
+	res, err := s.db.Exec(query, password, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.UserNotFound)
+	}
+
+	return nil
+}
+
+// UpdateProfile sets the profile fields of the user, empty values clear them.
+func (s *Storage) UpdateProfile(userId int64, displayName, avatarUrl, bio string) error {
+	const op = "storage.sqlite.UpdateProfile"
+
+	const query = `UPDATE users SET display_name = NULLIF(?, ''), avatar_url = NULLIF(?, ''), bio = NULLIF(?, '') WHERE id = ?`
+	res, err := s.db.Exec(query, displayName, avatarUrl, bio, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.UserNotFound)
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user along with its recovery codes, read cursors, reactions,
+// followed threads and mentions, the messages of the user are kept in the room histories.
+func (s *Storage) DeleteUser(userId int64) error {
+	const op = "storage.sqlite.DeleteUser"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM room_reads WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM message_reactions WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM thread_follows WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM mentions WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.UserNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.IsAdmin, &user.TotpSecret, &user.TotpEnabled,
+		&user.DisplayName, &user.AvatarUrl, &user.Bio, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/lib/db"
+	"github.com/guluzadehh/go_chat/internal/models"
+)
+
+// ThreadReplies returns up to limit replies in the thread of the message with an id lower
+// than before, oldest first. A non-positive before starts from the latest reply.
+func (s *Storage) ThreadReplies(rootId, before int64, limit int) ([]*models.Message, error) {
+	const op = "storage.sqlite.ThreadReplies"
+
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM messages
+		WHERE thread_root = ? AND id < ? AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT ?
+	`, messageColumns)
+	rows, err := s.db.Query(query, rootId, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	replies, err := scanMessages(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slices.Reverse(replies)
+
+	return replies, nil
+}
+
+// Threads sums up the replies to each of the messages, messages without replies are left out.
+func (s *Storage) Threads(rootIds []int64) (map[int64]*models.Thread, error) {
+	const op = "storage.sqlite.Threads"
+
+	threads := make(map[int64]*models.Thread)
+	if len(rootIds) == 0 {
+		return threads, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT m.thread_root, t.replies, m.created_at FROM messages m
+		JOIN (
+			SELECT COUNT(*) AS replies, MAX(id) AS last_id FROM messages
+			WHERE thread_root IN (%s) AND deleted_at IS NULL
+			GROUP BY thread_root
+		) t ON m.id = t.last_id
+	`, db.Placeholders(len(rootIds)))
+
+	args := make([]interface{}, 0, len(rootIds))
+	for _, id := range rootIds {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rootId int64
+		var thread models.Thread
+		if err := rows.Scan(&rootId, &thread.ReplyCount, &thread.LastReplyAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		threads[rootId] = &thread
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return threads, nil
+}
+
+// FollowThread subscribes the user to the replies in the thread of the message, it reports
+// whether the user hasn't been following it yet.
+func (s *Storage) FollowThread(rootId, userId int64) (bool, error) {
+	const op = "storage.sqlite.FollowThread"
+
+	const query = `INSERT OR IGNORE INTO thread_follows("message_id", "user_id", "created_at") VALUES(?, ?, ?)`
+	res, err := s.db.Exec(query, rootId, userId, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// UnfollowThread unsubscribes the user from the thread, it reports whether the user has
+// been following it.
+func (s *Storage) UnfollowThread(rootId, userId int64) (bool, error) {
+	const op = "storage.sqlite.UnfollowThread"
+
+	res, err := s.db.Exec(`DELETE FROM thread_follows WHERE message_id = ? AND user_id = ?`, rootId, userId)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected > 0, nil
+}
+
+// UnfollowRoomThreads unsubscribes the user from every thread of the room.
+func (s *Storage) UnfollowRoomThreads(roomUuid string, userId int64) error {
+	const op = "storage.sqlite.UnfollowRoomThreads"
+
+	const query = `DELETE FROM thread_follows WHERE user_id = ? AND message_id IN (SELECT id FROM messages WHERE room_uuid = ?)`
+	if _, err := s.db.Exec(query, userId, roomUuid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ThreadFollowers returns the ids of the users following the thread of the message.
+func (s *Storage) ThreadFollowers(rootId int64) ([]int64, error) {
+	const op = "storage.sqlite.ThreadFollowers"
+
+	rows, err := s.db.Query(`SELECT user_id FROM thread_follows WHERE message_id = ? ORDER BY user_id`, rootId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/guluzadehh/go_chat/internal/storage"
+)
+
+// SetTotpSecret enrolls the user with a new secret, two-factor authentication stays
+// disabled until EnableTotp.
+func (s *Storage) SetTotpSecret(userId int64, secret string) error {
+	const op = "storage.sqlite.SetTotpSecret"
+
+	const query = `UPDATE users SET totp_secret = ?, totp_enabled = FALSE WHERE id = ?`
+	if _, err := s.db.Exec(query, secret, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) EnableTotp(userId int64) error {
+	const op = "storage.sqlite.EnableTotp"
+
+	const query = `UPDATE users SET totp_enabled = TRUE WHERE id = ? AND totp_secret IS NOT NULL`
+	if _, err := s.db.Exec(query, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes drops the recovery codes of the user in favor of the given hashes.
+func (s *Storage) ReplaceRecoveryCodes(userId int64, hashes []string) error {
+	const op = "storage.sqlite.ReplaceRecoveryCodes"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO recovery_codes("user_id", "code_hash") VALUES(?, ?)`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, hash := range hashes {
+		if _, err := stmt.Exec(userId, hash); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code of the user as used.
+func (s *Storage) UseRecoveryCode(userId int64, hash string) error {
+	const op = "storage.sqlite.UseRecoveryCode"
+
+	const query = `UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	res, err := s.db.Exec(query, time.Now(), userId, hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.RecoveryCodeNotFound)
+	}
+
+	return nil
+}
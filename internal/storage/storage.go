@@ -3,7 +3,20 @@ package storage
 import "errors"
 
 var (
-	UserNotFound   = errors.New("user not found")
-	UsernameExists = errors.New("username is already taken")
-	RoomNotFound   = errors.New("room not found")
+	UserNotFound    = errors.New("user not found")
+	UsernameExists  = errors.New("username is already taken")
+	EmailExists     = errors.New("email is already taken")
+	RoomNotFound    = errors.New("room not found")
+	MessageNotFound = errors.New("message not found")
+	MessageExists   = errors.New("message with the client id already exists")
+	InviteNotFound  = errors.New("invite not found")
+	InviteExhausted = errors.New("invite has been used up")
+
+	ReactionLimitReached = errors.New("message has too many reactions")
+
+	RefreshTokenNotFound = errors.New("refresh token not found")
+	RefreshTokenReused   = errors.New("refresh token has already been used")
+
+	RecoveryCodeNotFound = errors.New("recovery code not found")
+	ResetTokenNotFound   = errors.New("password reset token not found")
 )
@@ -1,12 +1,18 @@
 package types
 
 import (
+	"time"
+
 	"github.com/guluzadehh/go_chat/internal/models"
 )
 
 type UserView struct {
-	Id       int64  `json:"id"`
-	Username string `json:"username"`
+	Id          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	AvatarUrl   string    `json:"avatar_url,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 func NewUser(u *models.User) *UserView {
@@ -15,27 +21,188 @@ func NewUser(u *models.User) *UserView {
 	}
 
 	return &UserView{
-		Id:       u.Id,
-		Username: u.Username,
+		Id:          u.Id,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		AvatarUrl:   u.AvatarUrl,
+		Bio:         u.Bio,
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+// MeView is the profile of the signed in user along with its account settings.
+type MeView struct {
+	*UserView
+	Email       string `json:"email,omitempty"`
+	IsAdmin     bool   `json:"is_admin"`
+	TotpEnabled bool   `json:"totp_enabled"`
+}
+
+func NewMe(u *models.User) *MeView {
+	if u == nil {
+		return nil
+	}
+
+	return &MeView{
+		UserView:    NewUser(u),
+		Email:       u.Email,
+		IsAdmin:     u.IsAdmin,
+		TotpEnabled: u.TotpEnabled,
 	}
 }
 
 type RoomView struct {
-	Uuid      string    `json:"uuid"`
-	Name      string    `json:"name"`
-	IsPrivate bool      `json:"is_private"`
-	Owner     *UserView `json:"owner"`
+	Uuid        string    `json:"uuid"`
+	Name        string    `json:"name"`
+	IsPrivate   bool      `json:"is_private"`
+	Owner       *UserView `json:"owner"`
+	OnlineCount int       `json:"online_count"`
+	UnreadCount int       `json:"unread_count"`
 }
 
-func NewRoom(r *models.Room, o *models.User) *RoomView {
+func NewRoom(r *models.Room, o *models.User, online, unread int) *RoomView {
 	if r == nil {
 		return nil
 	}
 
 	return &RoomView{
-		Uuid:      r.Uuid,
-		Name:      r.Name,
-		IsPrivate: r.IsPrivate(),
-		Owner:     NewUser(o),
+		Uuid:        r.Uuid,
+		Name:        r.Name,
+		IsPrivate:   r.IsPrivate(),
+		Owner:       NewUser(o),
+		OnlineCount: online,
+		UnreadCount: unread,
+	}
+}
+
+type InviteView struct {
+	Id        string    `json:"id"`
+	Token     string    `json:"token,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewInvite shows the invite, the token is only known when the invite is created.
+func NewInvite(i *models.Invite, token string) *InviteView {
+	if i == nil {
+		return nil
+	}
+
+	return &InviteView{
+		Id:        i.Id,
+		Token:     token,
+		Username:  i.Username,
+		MaxUses:   i.MaxUses,
+		Uses:      i.Uses,
+		ExpiresAt: i.ExpiresAt,
+	}
+}
+
+type LoginLockoutView struct {
+	Kind     string    `json:"kind"`
+	Subject  string    `json:"subject"`
+	Failures int64     `json:"failures"`
+	Until    time.Time `json:"until"`
+}
+
+func NewLoginLockout(l *models.LoginLockout) *LoginLockoutView {
+	if l == nil {
+		return nil
+	}
+
+	return &LoginLockoutView{
+		Kind:     l.Kind,
+		Subject:  l.Subject,
+		Failures: l.Failures,
+		Until:    l.Until,
+	}
+}
+
+type MessagePreview struct {
+	Id        int64     `json:"id"`
+	UserId    int64     `json:"user_id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewMessagePreview(m *models.Message) *MessagePreview {
+	if m == nil {
+		return nil
+	}
+
+	return &MessagePreview{
+		Id:        m.Id,
+		UserId:    m.UserId,
+		Text:      m.Text,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// DirectView is a direct conversation as seen by one of its users, User is the other one.
+type DirectView struct {
+	Uuid        string          `json:"uuid"`
+	User        *UserView       `json:"user"`
+	LastMessage *MessagePreview `json:"last_message"`
+	UnreadCount int             `json:"unread_count"`
+}
+
+func NewDirect(r *models.Room, peer *models.User, last *models.Message, unread int) *DirectView {
+	if r == nil {
+		return nil
+	}
+
+	return &DirectView{
+		Uuid:        r.Uuid,
+		User:        NewUser(peer),
+		LastMessage: NewMessagePreview(last),
+		UnreadCount: unread,
+	}
+}
+
+type ReactionView struct {
+	Emoji   string  `json:"emoji"`
+	Count   int     `json:"count"`
+	UserIds []int64 `json:"user_ids"`
+}
+
+func NewReactions(reactions []*models.Reaction) []*ReactionView {
+	views := make([]*ReactionView, 0, len(reactions))
+	for _, r := range reactions {
+		views = append(views, &ReactionView{
+			Emoji:   r.Emoji,
+			Count:   len(r.UserIds),
+			UserIds: r.UserIds,
+		})
+	}
+
+	return views
+}
+
+type ThreadView struct {
+	ReplyCount  int       `json:"reply_count"`
+	LastReplyAt time.Time `json:"last_reply_at"`
+}
+
+func NewThread(t *models.Thread) *ThreadView {
+	return &ThreadView{
+		ReplyCount:  t.ReplyCount,
+		LastReplyAt: t.LastReplyAt,
+	}
+}
+
+// MentionView is a message that mentions the user.
+type MentionView struct {
+	RoomUuid string          `json:"room_uuid"`
+	Message  *MessagePreview `json:"message"`
+	Read     bool            `json:"read"`
+}
+
+func NewMention(m *models.Mention) *MentionView {
+	return &MentionView{
+		RoomUuid: m.Message.RoomUuid,
+		Message:  NewMessagePreview(m.Message),
+		Read:     m.IsRead(),
 	}
 }
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_room_uuid_id;
+DROP TABLE IF EXISTS messages;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_user_id_client_id;
+ALTER TABLE messages DROP COLUMN client_id;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN client_id VARCHAR(64);
+
+CREATE INDEX idx_messages_user_id_client_id ON messages(user_id, client_id);
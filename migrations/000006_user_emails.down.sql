@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users DROP COLUMN email;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_users_email ON users(email);
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN display_name VARCHAR(64);
+ALTER TABLE users ADD COLUMN avatar_url VARCHAR(2048);
+ALTER TABLE users ADD COLUMN bio TEXT;
+ALTER TABLE users ADD COLUMN created_at DATETIME;
+
+UPDATE users SET created_at = CURRENT_TIMESTAMP;
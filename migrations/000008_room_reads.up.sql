@@ -0,0 +1,7 @@
+CREATE TABLE room_reads (
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    room_uuid VARCHAR(36) NOT NULL,
+    message_id INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, room_uuid)
+);
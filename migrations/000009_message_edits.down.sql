@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN deleted_at;
+ALTER TABLE messages DROP COLUMN edited_at;
\ No newline at end of file
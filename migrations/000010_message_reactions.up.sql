@@ -0,0 +1,7 @@
+CREATE TABLE message_reactions (
+    message_id INTEGER NOT NULL REFERENCES messages(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    emoji VARCHAR(32) NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (message_id, user_id, emoji)
+);
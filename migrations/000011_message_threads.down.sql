@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS thread_follows;
+DROP INDEX IF EXISTS idx_messages_thread_root;
+ALTER TABLE messages DROP COLUMN thread_root;
+ALTER TABLE messages DROP COLUMN reply_to;
\ No newline at end of file
@@ -0,0 +1,11 @@
+ALTER TABLE messages ADD COLUMN reply_to INTEGER REFERENCES messages(id);
+ALTER TABLE messages ADD COLUMN thread_root INTEGER REFERENCES messages(id);
+
+CREATE INDEX idx_messages_thread_root ON messages(thread_root);
+
+CREATE TABLE thread_follows (
+    message_id INTEGER NOT NULL REFERENCES messages(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (message_id, user_id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_mentions_user_id;
+DROP TABLE IF EXISTS mentions;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE mentions (
+    message_id INTEGER NOT NULL REFERENCES messages(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    room_uuid VARCHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL,
+    read_at DATETIME,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX idx_mentions_user_id ON mentions(user_id, message_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_user_id_room_uuid_client_id;
+CREATE INDEX idx_messages_user_id_client_id ON messages(user_id, client_id);
\ No newline at end of file
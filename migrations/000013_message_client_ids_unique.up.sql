@@ -0,0 +1,8 @@
+UPDATE messages SET client_id = NULL
+WHERE client_id IS NOT NULL AND id NOT IN (
+    SELECT MAX(id) FROM messages WHERE client_id IS NOT NULL GROUP BY user_id, room_uuid, client_id
+);
+
+DROP INDEX IF EXISTS idx_messages_user_id_client_id;
+
+CREATE UNIQUE INDEX idx_messages_user_id_room_uuid_client_id ON messages(user_id, room_uuid, client_id);